@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,11 +19,22 @@ var (
 	totalConns int64
 )
 
-// Invocado desde core.HandleWithError o en Middleware
+// Incrementa el contador de conexiones que reporta /status.
 func CountConn() {
 	atomic.AddInt64(&totalConns, 1)
 }
 
+// Middleware que cuenta cada conexión nueva (su primera solicitud) con CountConn.
+// Se registra con server.Use(advanced.CountConnections).
+func CountConnections(next core.Handle) core.Handle {
+	return func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		if req.Sequence <= 1 {
+			CountConn()
+		}
+		return next(req)
+	}
+}
+
 // SimulateHandler simula una tarea cuyo procesamiento toma 'seconds' segundos.
 // URL: /simulate?seconds=s&task=name
 func SimulateHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
@@ -45,8 +57,10 @@ func SimulateHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 		return core.BadRequest().Text("task is required"), nil
 	}
 
-	// Simular la tarea
-	time.Sleep(time.Duration(seconds) * time.Second)
+	// Simular la tarea; se abandona si el cliente se va o vence el plazo
+	if err := core.Sleep(req.Context(), time.Duration(seconds)*time.Second); err != nil {
+		return nil, err
+	}
 
 	// Construir respuesta JSON
 	resp := struct {
@@ -73,8 +87,10 @@ func SleepHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 		return core.BadRequest().Text("seconds must be >= 0"), nil
 	}
 
-	// Sleep real (o cero si seconds == 0)
-	time.Sleep(time.Duration(seconds) * time.Second)
+	// Sleep real (o cero si seconds == 0), interrumpido si se cancela la solicitud
+	if err := core.Sleep(req.Context(), time.Duration(seconds)*time.Second); err != nil {
+		return nil, err
+	}
 
 	// Respuesta simple en texto plano
 	return core.Ok().Text(fmt.Sprintf("slept %d seconds", seconds)), nil
@@ -108,17 +124,20 @@ func LoadTestHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 		return core.BadRequest().Text("sleep must be >= 0"), nil
 	}
 
-	// Lanzar n goroutines y medir tiempo
+	// Lanzar n goroutines y medir tiempo; todas terminan si se cancela la solicitud
 	var wg sync.WaitGroup
 	wg.Add(n)
 	start := time.Now()
 	for i := 0; i < n; i++ {
 		go func() {
-			time.Sleep(time.Duration(x) * time.Second)
+			core.Sleep(req.Context(), time.Duration(x)*time.Second)
 			wg.Done()
 		}()
 	}
 	wg.Wait()
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
 	durationMs := time.Since(start).Milliseconds()
 
 	// Construir JSON de salida
@@ -133,19 +152,100 @@ func LoadTestHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 
 // StatusHandler
 func StatusHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
+	return status(nil), nil
+}
+
+// NewStatusHandler crea un StatusHandler que además incluye las métricas del servidor.
+// Uso: server.Get("/status", advanced.NewStatusHandler(server.Stats))
+func NewStatusHandler(stats func() core.ServerStats) core.Handle {
+	return func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		current := stats()
+		return status(&current), nil
+	}
+}
+
+// status construye la respuesta de /status.
+func status(stats *core.ServerStats) *core.HttpResponse {
 	uptime := time.Since(startTime).Seconds()
+	load1 := loadAverage()
+	cpus := runtime.NumCPU()
 	resp := struct {
-		Uptime     float64 `json:"uptime_s"`
-		TotalConns int64   `json:"total_connections"`
-		PID        int     `json:"pid"`
-		Goroutines int     `json:"goroutines"`
+		Uptime     float64           `json:"uptime_s"`
+		TotalConns int64             `json:"total_connections"`
+		PID        int               `json:"pid"`
+		Goroutines int               `json:"goroutines"`
+		CPUs       int               `json:"num_cpu"`
+		Load1      float64           `json:"load1"`
+		CPULoad    float64           `json:"cpu_load"`
+		Server     *core.ServerStats `json:"server,omitempty"`
 	}{
 		uptime,
 		atomic.LoadInt64(&totalConns),
 		os.Getpid(),
 		runtime.NumGoroutine(),
+		cpus,
+		load1,
+		processCPULoad(),
+		stats,
 	}
-	return core.Ok().JsonObj(resp), nil
+	return core.Ok().JsonObj(resp)
+}
+
+// loadAverage devuelve la carga media del sistema del último minuto,
+// leída de /proc/loadavg, o 0 donde no está disponible.
+func loadAverage() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load
+}
+
+// Intervalo mínimo entre dos muestras de CPU: con menos, la medida es ruido.
+const cpuSampleInterval = 100 * time.Millisecond
+
+// Última muestra del tiempo de CPU del proceso y la carga calculada con ella.
+var cpuSample struct {
+	mu   sync.Mutex
+	at   time.Time
+	used time.Duration
+	load float64
+}
+
+// processCPULoad devuelve la fracción de CPU que usó este proceso desde la
+// consulta anterior (desde el arranque en la primera), dividida entre el
+// número de CPUs: 1 es todas las CPUs ocupadas. A diferencia de /proc/loadavg,
+// no incluye la carga de otros procesos del host.
+func processCPULoad() float64 {
+	used, ok := processCPUTime()
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+
+	cpuSample.mu.Lock()
+	defer cpuSample.mu.Unlock()
+
+	since, before := cpuSample.at, cpuSample.used
+	if since.IsZero() {
+		since, before = startTime, 0
+	}
+	elapsed := now.Sub(since)
+	if elapsed < cpuSampleInterval {
+		return cpuSample.load
+	}
+
+	cpuSample.at, cpuSample.used = now, used
+	cpuSample.load = float64(used-before) / float64(elapsed) / float64(runtime.NumCPU())
+	return cpuSample.load
 }
 
 // HelpHandler (/help)
@@ -154,6 +254,8 @@ func HelpHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 		"GET  /fibonacci?num=",
 		"POST /createfile?name=&content=&repeat=",
 		"DELETE /deletefile?name=",
+		"POST /files/{name}?content=&repeat=",
+		"DELETE /files/{name}",
 		"GET  /reverse?text=",
 		"GET  /toupper?text=",
 		"GET  /hash?text=",
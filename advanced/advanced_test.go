@@ -1,9 +1,12 @@
 package advanced
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/KateGF/Http-Server-Project-SO/core"
 	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -155,6 +158,29 @@ func TestSleepHandler_Errors(t *testing.T) {
 	}
 }
 
+func TestLongHandlers_Cancelled(t *testing.T) {
+	cases := []struct {
+		path    string
+		handler core.Handle
+	}{
+		{"/simulate?seconds=60&task=t", SimulateHandler},
+		{"/sleep?seconds=60", SleepHandler},
+		{"/loadtest?tasks=4&sleep=60", LoadTestHandler},
+	}
+	for _, tc := range cases {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err := tc.handler(makeReq(tc.path).WithContext(ctx))
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: want context.DeadlineExceeded; got %v", tc.path, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: want to stop at the deadline; took %v", tc.path, elapsed)
+		}
+	}
+}
+
 func TestLoadTestHandler_Success(t *testing.T) {
 	// Con sleep=0 no tardará, así que es rápido
 	req := makeReq("/loadtest?tasks=5&sleep=0")
@@ -220,6 +246,8 @@ func TestStatusHandler(t *testing.T) {
 			TotalConns int64   `json:"total_connections"`
 			PID        int     `json:"pid"`
 			Goroutines int     `json:"goroutines"`
+			CPUs       int     `json:"num_cpu"`
+			CPULoad    float64 `json:"cpu_load"`
 		}
 		if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
 			t.Fatalf("status JSON: %v", err)
@@ -236,6 +264,9 @@ func TestStatusHandler(t *testing.T) {
 		if body.Goroutines < 1 {
 			t.Errorf("invalid Goroutines %d", body.Goroutines)
 		}
+		if body.CPUs < 1 || body.CPULoad < 0 {
+			t.Errorf("invalid CPU load %d/%f", body.CPUs, body.CPULoad)
+		}
 
 		// esperamos uptime creciente
 		if body.Uptime <= 0 {
@@ -247,6 +278,22 @@ func TestStatusHandler(t *testing.T) {
 	}
 }
 
+func TestProcessCPULoad(t *testing.T) {
+	if _, ok := processCPUTime(); !ok {
+		t.Skip("process CPU time not available")
+	}
+	processCPULoad()
+
+	// Ocupamos una CPU durante más que el intervalo de muestreo
+	deadline := time.Now().Add(2 * cpuSampleInterval)
+	for time.Now().Before(deadline) {
+	}
+
+	if load := processCPULoad(); load <= 0 {
+		t.Errorf("cpu_load: want >0 after busy loop; got %f", load)
+	}
+}
+
 func TestHelpHandler(t *testing.T) {
 	req := makeReq("/help")
 	res, _ := HelpHandler(req)
@@ -271,3 +318,41 @@ func TestHelpHandler(t *testing.T) {
 		t.Errorf("help missing fibonacci command: %+v", body.Commands)
 	}
 }
+
+func TestCountConnections(t *testing.T) {
+	next := func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok(), nil
+	}
+	handle := CountConnections(next)
+
+	before := atomic.LoadInt64(&totalConns)
+
+	// Tres solicitudes sobre la misma conexión cuentan una sola conexión
+	for seq := 1; seq <= 3; seq++ {
+		req := makeReq("/status")
+		req.Sequence = seq
+		handle(req)
+	}
+
+	if got := atomic.LoadInt64(&totalConns) - before; got != 1 {
+		t.Errorf("want 1 new connection; got %d", got)
+	}
+}
+
+func TestNewStatusHandler(t *testing.T) {
+	handle := NewStatusHandler(func() core.ServerStats {
+		return core.ServerStats{Panics: 2}
+	})
+	res, _ := handle(makeReq("/status"))
+	var body struct {
+		Server struct {
+			Panics int64 `json:"panics"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+		t.Fatalf("status JSON: %v", err)
+	}
+	if body.Server.Panics != 2 {
+		t.Errorf("want 2 panics; got %d", body.Server.Panics)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package advanced
+
+import "time"
+
+// processCPUTime no está disponible fuera de unix: /status reporta cpu_load 0.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
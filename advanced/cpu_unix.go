@@ -0,0 +1,18 @@
+//go:build unix
+
+package advanced
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime devuelve el tiempo de CPU (usuario y sistema) que lleva
+// consumido el proceso.
+func processCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Indica si la cabecera Transfer-Encoding termina en "chunked".
+// Según HTTP/1.1, chunked debe ser siempre la última codificación aplicada.
+func isChunked(transferEncoding string) bool {
+	codings := strings.Split(transferEncoding, ",")
+	last := strings.TrimSpace(codings[len(codings)-1])
+	return strings.EqualFold(last, "chunked")
+}
+
+// Longitud máxima de una línea de tamaño de bloque, con sus extensiones.
+const maxChunkLineBytes = 4096
+
+// Lee un cuerpo con codificación chunked desde el lector.
+// Devuelve el cuerpo completo y las cabeceras finales (trailers), si las hay.
+// Con los límites de config (0 = sin límite), un cuerpo mayor que MaxBodyBytes
+// o una línea de tamaño demasiado larga se rechazan con un HttpError 413, y
+// unos trailers que superan MaxHeaderBytes o MaxHeaderCount, con un 431.
+func readChunkedBody(reader *bufio.Reader, config Config) (string, Header, error) {
+	var body strings.Builder
+	maxBytes := config.MaxBodyBytes
+
+	for {
+		// Cada bloque empieza con su tamaño en hexadecimal, con extensiones opcionales tras ';'
+		line, err := readChunkLine(reader, maxChunkLineBytes)
+		var httpErr *HttpError
+		if errors.As(err, &httpErr) {
+			return "", nil, NewHttpError(413, "Content Too Large", "chunk size line exceeds %d bytes", maxChunkLineBytes)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		sizeStr, _, _ := strings.Cut(line, ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 64)
+		if err != nil || size < 0 {
+			return "", nil, fmt.Errorf("bad chunk size: %q", line)
+		}
+
+		// Un bloque de tamaño cero marca el final del cuerpo
+		if size == 0 {
+			break
+		}
+
+		// Se compara con lo que queda del límite: sumar el tamaño podría desbordarse
+		if maxBytes > 0 && size > maxBytes-int64(body.Len()) {
+			return "", nil, NewHttpError(413, "Content Too Large", "body exceeds %d bytes", maxBytes)
+		}
+
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return "", nil, fmt.Errorf("can't read chunk: %w", err)
+		}
+
+		// Cada bloque termina con CRLF
+		crlf, err := readChunkLine(reader, maxChunkLineBytes)
+		if err != nil || crlf != "" {
+			return "", nil, fmt.Errorf("missing chunk terminator")
+		}
+	}
+
+	// Lee las cabeceras finales hasta la línea vacía, con los límites de las cabeceras
+	trailers := make(Header)
+	read, count := 0, 0
+	for {
+		// Con el límite agotado queda 1 byte, no 0 (que sería sin límite)
+		limit := 0
+		if config.MaxHeaderBytes > 0 {
+			limit = max(config.MaxHeaderBytes-read, 1)
+		}
+
+		line, err := readChunkLine(reader, limit)
+		if err != nil {
+			return "", nil, err
+		}
+		read += len(line) + 2
+
+		if line == "" {
+			break
+		}
+
+		count++
+		if config.MaxHeaderCount > 0 && count > config.MaxHeaderCount {
+			return "", nil, NewHttpError(431, "Request Header Fields Too Large", "too many trailers")
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			// Ignora líneas mal formadas
+			continue
+		}
+
+		trailers.Add(k, strings.TrimSpace(v))
+	}
+
+	return body.String(), trailers, nil
+}
+
+// Lee una línea de la codificación chunked sin el CRLF final.
+// Si limit es positivo y la línea lo supera, devuelve el HttpError 431 de readLine.
+func readChunkLine(reader *bufio.Reader, limit int) (string, error) {
+	line, err := readLine(reader, limit)
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("can't read chunk: %w", err)
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	line = strings.TrimSuffix(line, "\n")
+
+	return line, nil
+}
+
+// Escritor que envía cada escritura como un bloque de la codificación chunked.
+type chunkedWriter struct {
+	writer io.Writer
+}
+
+// Crea un chunkedWriter que escribe sobre el escritor dado.
+func newChunkedWriter(writer io.Writer) *chunkedWriter {
+	return &chunkedWriter{writer: writer}
+}
+
+// Escribe los datos como un único bloque.
+// Una escritura vacía no produce nada, ya que un bloque de tamaño cero terminaría el cuerpo.
+func (cw *chunkedWriter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	if _, err := fmt.Fprintf(cw.writer, "%x\r\n", len(data)); err != nil {
+		return 0, err
+	}
+
+	n, err := cw.writer.Write(data)
+	if err != nil {
+		return n, err
+	}
+
+	if _, err := io.WriteString(cw.writer, "\r\n"); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// Escribe el bloque final de tamaño cero que cierra el cuerpo.
+func (cw *chunkedWriter) Close() error {
+	_, err := io.WriteString(cw.writer, "0\r\n\r\n")
+	return err
+}
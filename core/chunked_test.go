@@ -0,0 +1,139 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadRequestChunked(t *testing.T) {
+	// Arrange
+	conn1, conn2 := net.Pipe()
+
+	defer conn2.Close()
+
+	go func() {
+		conn1.Write([]byte("POST /upload HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n" +
+			"4\r\nWiki\r\n" +
+			"6;ext=1\r\npedia \r\n" +
+			"E\r\nin \r\n\r\nchunks.\r\n" +
+			"0\r\nChecksum: abc\r\n\r\n"))
+		conn1.Close()
+	}()
+
+	// Act
+	request, err := ReadRequest(conn2)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, %v", err)
+	}
+
+	expected := "Wikipedia in \r\n\r\nchunks."
+	if request.Body != expected {
+		t.Errorf("Expected body to be %q, not %q", expected, request.Body)
+	}
+
+	if request.Trailers.Get("Checksum") != "abc" {
+		t.Errorf("Expected Checksum trailer to be abc, not %q", request.Trailers.Get("Checksum"))
+	}
+}
+
+var RejectChunkedTests = []string{
+	// bad chunk size
+	"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nZ\r\nabc\r\n0\r\n\r\n",
+	// missing chunk terminator
+	"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabcd\r\n0\r\n\r\n",
+	// truncated body
+	"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n10\r\nabc",
+	// unsupported transfer encoding
+	"POST / HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n",
+	// both content length and transfer encoding
+	"POST / HTTP/1.1\r\nContent-Length: 3\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n",
+}
+
+func TestReadRequestChunkedReject(t *testing.T) {
+	for i, input := range RejectChunkedTests {
+		t.Run(fmt.Sprintf("TestReadRequestChunkedReject %d", i), func(t *testing.T) {
+			// Arrange
+			conn1, conn2 := net.Pipe()
+
+			defer conn2.Close()
+
+			go func() {
+				conn1.Write([]byte(input))
+				conn1.Close()
+			}()
+
+			// Act
+			request, err := ReadRequest(conn2)
+
+			// Assert
+			if request != nil {
+				t.Errorf("Expected no request, not %v", request)
+			}
+
+			if err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+}
+
+func TestChunkedWriter(t *testing.T) {
+	// Arrange
+	var buffer bytes.Buffer
+	writer := newChunkedWriter(&buffer)
+
+	// Act
+	writer.Write([]byte("Hello, "))
+	writer.Write([]byte(""))
+	writer.Write([]byte("chunked world!"))
+	writer.Close()
+
+	// Assert
+	expected := "7\r\nHello, \r\ne\r\nchunked world!\r\n0\r\n\r\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected %q, not %q", expected, buffer.String())
+	}
+}
+
+func TestWriteResponseBodyReader(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"HTTP/1.1", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nstream\r\n0\r\n\r\n"},
+		{"HTTP/1.0", "HTTP/1.0 200 OK\r\n\r\nstream"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			// Arrange
+			conn1, conn2 := net.Pipe()
+
+			response := Ok().SetBodyReader(strings.NewReader("stream"))
+			response.Version = test.version
+
+			// Act
+			go func() {
+				response.WriteResponse(conn1)
+				conn1.Close()
+			}()
+
+			data, err := io.ReadAll(conn2)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, %v", err)
+			}
+
+			if string(data) != test.expected {
+				t.Errorf("Expected %q, not %q", test.expected, data)
+			}
+		})
+	}
+}
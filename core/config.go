@@ -0,0 +1,44 @@
+package core
+
+import "time"
+
+// Configuración del servidor HTTP.
+// Un valor cero en cualquier límite significa "sin límite".
+type Config struct {
+	IdleTimeout        time.Duration // Tiempo máximo de espera entre solicitudes de una conexión persistente (menos si hay conexiones en cola)
+	MaxRequestsPerConn int           // Número máximo de solicitudes atendidas por conexión
+
+	ReadHeaderTimeout time.Duration // Tiempo máximo para leer la línea de solicitud y las cabeceras
+	BodyReadTimeout   time.Duration // Tiempo máximo para leer el cuerpo de la solicitud
+	WriteTimeout      time.Duration // Tiempo máximo de cada escritura de la respuesta
+
+	MaxHeaderBytes int   // Tamaño máximo de la línea de solicitud y las cabeceras (431)
+	MaxHeaderCount int   // Número máximo de cabeceras (431)
+	MaxBodyBytes   int64 // Tamaño máximo del cuerpo de la solicitud (413)
+
+	Workers    int           // Goroutines que atienden conexiones (0 = una goroutine por conexión)
+	QueueSize  int           // Conexiones aceptadas que pueden esperar un worker
+	RetryAfter time.Duration // Espera sugerida en Retry-After cuando la cola está llena
+
+	HandleSignals   bool          // Si Start instala su propio manejo de SIGINT/SIGTERM
+	ShutdownTimeout time.Duration // Plazo del Shutdown iniciado por una señal
+}
+
+// Devuelve la configuración predeterminada del servidor.
+func DefaultConfig() Config {
+	return Config{
+		IdleTimeout:        5 * time.Second,
+		MaxRequestsPerConn: 100,
+		ReadHeaderTimeout:  10 * time.Second,
+		BodyReadTimeout:    30 * time.Second,
+		WriteTimeout:       30 * time.Second,
+		MaxHeaderBytes:     1 << 20,
+		MaxHeaderCount:     100,
+		MaxBodyBytes:       10 << 20,
+		Workers:            64,
+		QueueSize:          128,
+		RetryAfter:         time.Second,
+		HandleSignals:      true,
+		ShutdownTimeout:    10 * time.Second,
+	}
+}
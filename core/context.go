@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Cabecera con el plazo absoluto de la solicitud, en formato RFC 3339
+// (ej. "2025-06-01T12:00:00.5Z"). Pasado el plazo, el contexto de la
+// solicitud se cancela y el resultado ya no le sirve al cliente.
+const DeadlineHeader = "X-Request-Deadline"
+
+// Convierte un plazo al formato de DeadlineHeader.
+func FormatDeadline(deadline time.Time) string {
+	return deadline.UTC().Format(time.RFC3339Nano)
+}
+
+// Lee el plazo de DeadlineHeader.
+func ParseDeadline(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// Devuelve el contexto de la solicitud. El servidor lo cancela cuando el
+// cliente cierra la conexión, cuando vence el plazo de DeadlineHeader o al
+// terminar de escribir la respuesta. Nunca es nil.
+func (request *HttpRequest) Context() context.Context {
+	if request.ctx == nil {
+		return context.Background()
+	}
+	return request.ctx
+}
+
+// Devuelve una copia de la solicitud con el contexto dado.
+func (request *HttpRequest) WithContext(ctx context.Context) *HttpRequest {
+	shallow := *request
+	shallow.ctx = ctx
+	return &shallow
+}
+
+// Crea el contexto de una solicitud, con el plazo de DeadlineHeader si lo trae.
+func requestContext(request *HttpRequest) (context.Context, context.CancelFunc, error) {
+	value := request.Headers.Get(DeadlineHeader)
+	if value == "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		return ctx, cancel, nil
+	}
+
+	deadline, err := ParseDeadline(value)
+	if err != nil {
+		return nil, nil, NewHttpError(400, "Bad Request", "invalid %s: %q", DeadlineHeader, value)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	return ctx, cancel, nil
+}
+
+// Espera d o hasta que ctx termine, y entonces devuelve su error. Si ctx ya
+// terminó, vuelve de inmediato aunque d sea 0.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Plazo en el pasado que interrumpe una lectura bloqueada.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Vigila la conexión mientras se atiende una solicitud y llama a cancel si el
+// cliente la cierra. El cuerpo ya se leyó entero, así que lo único que puede
+// llegar es la siguiente solicitud (que se queda en el buffer) o el cierre.
+// Devuelve la función que detiene la vigilancia; hay que llamarla antes de
+// volver a leer de la conexión.
+func watchConn(conn net.Conn, reader *bufio.Reader, cancel context.CancelFunc) (stop func()) {
+	conn.SetReadDeadline(time.Time{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var netErr net.Error
+		if _, err := reader.Peek(1); err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			cancel()
+		}
+	}()
+
+	return func() {
+		conn.SetReadDeadline(aLongTimeAgo)
+		<-done
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestContextDefault(t *testing.T) {
+	// Arrange
+	request := schedulerRequest()
+
+	// Act
+	ctx := request.Context()
+
+	// Assert
+	if ctx == nil || ctx.Err() != nil {
+		t.Errorf("Expected a live background context, not %v", ctx)
+	}
+}
+
+func TestHandleRequestDeadline(t *testing.T) {
+	future := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	past := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus string
+		wantBody   string
+	}{
+		{"no deadline", "", "200 OK", "none"},
+		{"future deadline", DeadlineHeader + ": " + FormatDeadline(future) + "\r\n", "200 OK", FormatDeadline(future)},
+		{"expired deadline", DeadlineHeader + ": " + FormatDeadline(past) + "\r\n", "504 Gateway Timeout", "request deadline exceeded"},
+		{"invalid deadline", DeadlineHeader + ": tomorrow\r\n", "400 Bad Request", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange: el manejador devuelve el plazo de su contexto, o su error
+			server := NewHttpServer()
+			server.Get("/deadline", func(request *HttpRequest) (*HttpResponse, error) {
+				ctx := request.Context()
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				deadline, ok := ctx.Deadline()
+				if !ok {
+					return Ok().Text("none"), nil
+				}
+				return Ok().Text(FormatDeadline(deadline)), nil
+			})
+
+			// Act
+			response := roundTrip(t, server, "GET /deadline HTTP/1.1\r\n"+test.header+"Connection: close\r\n\r\n")
+
+			// Assert: los errores de lectura se responden con HTTP/1.0
+			_, status, _ := strings.Cut(response, " ")
+			if !strings.HasPrefix(status, test.wantStatus) {
+				t.Errorf("Expected status %s, not %q", test.wantStatus, response)
+			}
+			if !strings.HasSuffix(response, test.wantBody) {
+				t.Errorf("Expected body %q, not %q", test.wantBody, response)
+			}
+		})
+	}
+}
+
+func TestHandleCancelsOnClientDisconnect(t *testing.T) {
+	// Arrange: el manejador espera hasta que se cancele su contexto
+	server := NewHttpServer()
+	cancelled := make(chan error, 1)
+	started := make(chan struct{})
+	server.Get("/wait", func(request *HttpRequest) (*HttpResponse, error) {
+		close(started)
+		select {
+		case <-request.Context().Done():
+			cancelled <- request.Context().Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+		return Ok(), nil
+	})
+
+	client, conn := net.Pipe()
+	go server.Handle(conn)
+	fmt.Fprint(client, "GET /wait HTTP/1.1\r\nHost: test\r\n\r\n")
+	<-started
+
+	// Act: el cliente se va sin esperar la respuesta
+	client.Close()
+
+	// Assert
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, not %v", err)
+	}
+}
+
+func TestHandleKeepAliveWithPipelinedRequests(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		if err := request.Context().Err(); err != nil {
+			return nil, err
+		}
+		return Ok().Text("pong"), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+	go server.Handle(conn)
+
+	// Act: la segunda solicitud llega mientras se atiende la primera
+	go fmt.Fprint(client, "GET /ping HTTP/1.1\r\nHost: test\r\n\r\nGET /ping HTTP/1.1\r\nHost: test\r\n\r\n")
+
+	// Assert: la vigilancia de la conexión no consume ni cancela la siguiente solicitud
+	reader := bufio.NewReader(client)
+	for i := 0; i < 2; i++ {
+		status, _, body := readTestResponse(t, reader)
+		if status != "HTTP/1.1 200 OK" || body != "pong" {
+			t.Errorf("Expected 200 pong, not %s %s", status, body)
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		duration time.Duration
+		expected error
+	}{
+		{"elapsed", context.Background(), time.Millisecond, nil},
+		{"cancelled before a long wait", cancelled, time.Hour, context.Canceled},
+		{"cancelled before a zero wait", cancelled, 0, context.Canceled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			err := Sleep(test.ctx, test.duration)
+
+			// Assert
+			if err != test.expected {
+				t.Errorf("Expected %v, not %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Error de protocolo que el servidor responde con un código de estado concreto.
+type HttpError struct {
+	StatusCode int    // Código de estado de la respuesta (ej. 413)
+	StatusText string // Texto del estado (ej. "Content Too Large")
+	Err        error  // Causa del error
+}
+
+// Crea un HttpError con el estado y el mensaje dados.
+func NewHttpError(statusCode int, statusText string, format string, args ...any) *HttpError {
+	return &HttpError{
+		StatusCode: statusCode,
+		StatusText: statusText,
+		Err:        fmt.Errorf(format, args...),
+	}
+}
+
+func (e *HttpError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HttpError) Unwrap() error {
+	return e.Err
+}
+
+// Convierte el error de lectura de una solicitud en la respuesta que recibe el cliente:
+// 408 si se agotó un plazo de lectura, el estado del HttpError si lo hay y 400 en otro caso.
+func errorResponse(err error) *HttpResponse {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return NewHttpResponse(408, "Request Timeout", "").Text("request timeout")
+	}
+
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return NewHttpResponse(httpErr.StatusCode, httpErr.StatusText, "").Text(httpErr.Error())
+	}
+
+	return BadRequest().Text(err.Error())
+}
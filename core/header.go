@@ -0,0 +1,120 @@
+package core
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// Cabeceras HTTP. Las claves se guardan en forma canónica ("content-length"
+// se guarda como "Content-Length") y cada clave puede tener varios valores,
+// en el orden en que se recibieron o añadieron.
+type Header map[string][]string
+
+// Crea un Header a partir de un mapa de un solo valor por clave.
+func HeaderFrom(values map[string]string) Header {
+	header := make(Header, len(values))
+	for key, value := range values {
+		header.Set(key, value)
+	}
+	return header
+}
+
+// Devuelve la forma canónica de una clave de cabecera: la primera letra y
+// cada letra tras un '-' en mayúscula, el resto en minúscula.
+// Las claves con caracteres no válidos en un token se devuelven sin cambios.
+func CanonicalHeaderKey(key string) string {
+	for i := 0; i < len(key); i++ {
+		if !isTokenChar(key[i]) {
+			return key
+		}
+	}
+
+	canonical := []byte(key)
+	upper := true
+	for i, c := range canonical {
+		if upper && 'a' <= c && c <= 'z' {
+			canonical[i] = c - ('a' - 'A')
+		} else if !upper && 'A' <= c && c <= 'Z' {
+			canonical[i] = c + ('a' - 'A')
+		}
+		upper = c == '-'
+	}
+
+	return string(canonical)
+}
+
+// Indica si el byte puede formar parte de un token HTTP (RFC 9110).
+func isTokenChar(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// Devuelve el primer valor de la clave, o una cadena vacía si no existe.
+func (header Header) Get(key string) string {
+	values := header[CanonicalHeaderKey(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Devuelve todos los valores de la clave.
+func (header Header) Values(key string) []string {
+	return header[CanonicalHeaderKey(key)]
+}
+
+// Indica si la cabecera existe.
+func (header Header) Has(key string) bool {
+	_, ok := header[CanonicalHeaderKey(key)]
+	return ok
+}
+
+// Añade un valor a la clave, conservando los existentes.
+func (header Header) Add(key, value string) {
+	key = CanonicalHeaderKey(key)
+	header[key] = append(header[key], value)
+}
+
+// Reemplaza todos los valores de la clave por el valor dado.
+func (header Header) Set(key, value string) {
+	header[CanonicalHeaderKey(key)] = []string{value}
+}
+
+// Elimina la clave y todos sus valores.
+func (header Header) Del(key string) {
+	delete(header, CanonicalHeaderKey(key))
+}
+
+// Devuelve una copia independiente de las cabeceras.
+func (header Header) Clone() Header {
+	clone := make(Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+// Escribe las cabeceras ordenadas por clave, una línea por valor,
+// de modo que las cabeceras repetidas (ej. Set-Cookie) se envían por separado.
+// Los saltos de línea dentro de un valor se reemplazan por espacios.
+func (header Header) write(writer io.Writer) error {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+			if _, err := io.WriteString(writer, key+": "+value+"\r\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
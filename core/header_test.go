@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalHeaderKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"content-length", "Content-Length"},
+		{"CONTENT-TYPE", "Content-Type"},
+		{"x-request-id", "X-Request-Id"},
+		{"Host", "Host"},
+		{"bad key", "bad key"},
+	}
+
+	for _, test := range tests {
+		if got := CanonicalHeaderKey(test.key); got != test.expected {
+			t.Errorf("Expected %q to be %q, not %q", test.key, test.expected, got)
+		}
+	}
+}
+
+func TestHeaderMultipleValues(t *testing.T) {
+	// Arrange
+	header := make(Header)
+
+	// Act
+	header.Add("set-cookie", "a=1")
+	header.Add("Set-Cookie", "b=2")
+	header.Set("content-type", "text/plain")
+
+	// Assert
+	if values := header.Values("SET-COOKIE"); len(values) != 2 || values[0] != "a=1" || values[1] != "b=2" {
+		t.Errorf("Expected both cookies, not %v", values)
+	}
+	if header.Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected Content-Type to be text/plain, not %q", header.Get("Content-Type"))
+	}
+
+	header.Del("set-cookie")
+	if header.Has("Set-Cookie") {
+		t.Errorf("Expected Set-Cookie to be deleted")
+	}
+}
+
+func TestResponseRepeatedHeaders(t *testing.T) {
+	// Arrange
+	response := Ok().AddHeader("Set-Cookie", "a=1").AddHeader("Set-Cookie", "b=2")
+
+	// Act
+	str := response.String()
+
+	// Assert
+	if !strings.Contains(str, "Set-Cookie: a=1\r\nSet-Cookie: b=2\r\n") {
+		t.Errorf("Expected one line per cookie, got %q", str)
+	}
+}
+
+func TestReadRequestHeaderCase(t *testing.T) {
+	// Arrange
+	raw := "POST /files HTTP/1.1\r\ncontent-length: 5\r\naccept: text/plain\r\nAccept: application/json\r\n\r\nhello"
+
+	// Act
+	request, err := ReadRequestFrom(bufio.NewReader(strings.NewReader(raw)))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, %v", err)
+	}
+	if request.Body != "hello" {
+		t.Errorf("Expected body to be hello, not %q", request.Body)
+	}
+	if accept := request.Headers.Values("Accept"); len(accept) != 2 {
+		t.Errorf("Expected 2 Accept values, not %v", accept)
+	}
+}
+
+func TestReadRequestConflictingContentLength(t *testing.T) {
+	// Arrange
+	raw := "POST /files HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello!"
+
+	// Act
+	_, err := ReadRequestFrom(bufio.NewReader(strings.NewReader(raw)))
+
+	// Assert
+	if err == nil {
+		t.Errorf("Expected error for conflicting Content-Length")
+	}
+}
@@ -2,11 +2,16 @@ package core
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -25,18 +30,34 @@ var HTTP_METHODS = map[string]bool{
 // Representa una solicitud HTTP recibida.
 // Contiene el método, el objetivo (URL), las cabeceras y el cuerpo de la solicitud.
 type HttpRequest struct {
-	Method  string            // Método HTTP (GET, POST, etc.)
-	Target  *url.URL          // URL objetivo de la solicitud
-	Headers map[string]string // Cabeceras HTTP como un mapa de clave-valor
-	Body    string            // Cuerpo de la solicitud (si existe)
+	Method  string   // Método HTTP (GET, POST, etc.)
+	Target  *url.URL // URL objetivo de la solicitud
+	Version string   // Versión del protocolo (HTTP/1.0 o HTTP/1.1)
+	Headers Header   // Cabeceras HTTP, con claves canónicas y varios valores por clave
+	Body    string   // Cuerpo de la solicitud (si existe)
+
+	Trailers Header            // Cabeceras finales de un cuerpo chunked (si existen)
+	Params   map[string]string // Valores capturados por los parámetros y comodines de la ruta
+
+	ID         string // Identificador de la solicitud para correlacionar registros (lo asigna el servidor)
+	RemoteAddr string // Dirección del cliente (la asigna el servidor)
+	Sequence   int    // Posición de la solicitud dentro de su conexión (1 = primera)
+
+	TLS *tls.ConnectionState // Estado de la conexión TLS, con el certificado del cliente si lo presentó (nil sin TLS)
+
+	ctx context.Context // Contexto de la solicitud (ver Context)
 }
 
+// Error devuelto cuando la conexión se cierra antes de recibir una solicitud.
+var ErrEmptyRequest = errors.New("empty request")
+
 // Crea una nueva instancia de HttpRequest.
+// Las claves de las cabeceras se convierten a su forma canónica.
 func NewHttpRequest(method string, target *url.URL, header map[string]string, body string) *HttpRequest {
 	return &HttpRequest{
 		Method:  method,
 		Target:  target,
-		Headers: header,
+		Headers: HeaderFrom(header),
 		Body:    body,
 	}
 }
@@ -44,13 +65,41 @@ func NewHttpRequest(method string, target *url.URL, header map[string]string, bo
 // Lee una solicitud HTTP completa desde una conexión de red.
 // Devuelve un puntero a HttpRequest o un error si ocurre algún problema.
 func ReadRequest(conn net.Conn) (*HttpRequest, error) {
-	lines := make([]string, 0)
+	return ReadRequestFrom(bufio.NewReader(conn))
+}
 
-	reader := bufio.NewReader(conn)
+// Lee una solicitud HTTP completa desde un lector con búfer.
+// Permite leer varias solicitudes seguidas de la misma conexión sin perder
+// los bytes que el búfer ya haya leído. No aplica límites de tamaño.
+func ReadRequestFrom(reader *bufio.Reader) (*HttpRequest, error) {
+	request, err := readRequestHead(reader, Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readRequestBody(request, reader, Config{}); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Lee la línea de solicitud y las cabeceras, respetando MaxHeaderBytes y MaxHeaderCount.
+// Devuelve un HttpError 431 si se supera alguno de los dos límites.
+func readRequestHead(reader *bufio.Reader, config Config) (*HttpRequest, error) {
+	lines := make([]string, 0)
+	read := 0
 
 	// Lee las líneas de la cabecera hasta encontrar una línea vacía
 	for {
-		line, err := reader.ReadString('\n')
+		// Con el límite agotado queda 1 byte, no 0 (que sería sin límite)
+		limit := 0
+		if config.MaxHeaderBytes > 0 {
+			limit = max(config.MaxHeaderBytes-read, 1)
+		}
+
+		line, err := readLine(reader, limit)
+		read += len(line)
 
 		// Si es fin de archivo (EOF), puede ser normal si la conexión se cierra
 		if errors.Is(err, io.EOF) {
@@ -72,11 +121,16 @@ func ReadRequest(conn net.Conn) (*HttpRequest, error) {
 		}
 
 		lines = append(lines, line)
+
+		// La primera línea es la línea de solicitud; el resto son cabeceras
+		if config.MaxHeaderCount > 0 && len(lines)-1 > config.MaxHeaderCount {
+			return nil, NewHttpError(431, "Request Header Fields Too Large", "too many headers")
+		}
 	}
 
 	// Si no se leyeron líneas, la solicitud está vacía
 	if len(lines) == 0 {
-		return nil, fmt.Errorf("empty request")
+		return nil, ErrEmptyRequest
 	}
 
 	// Une las líneas de la cabecera y añade el doble salto de línea final
@@ -88,21 +142,68 @@ func ReadRequest(conn net.Conn) (*HttpRequest, error) {
 		return nil, fmt.Errorf("can't parse request: %w", err)
 	}
 
-	// Si el método es POST, exige Content-Length
-	if request.Method == "POST" {
-		if _, ok := request.Headers["Content-Length"]; !ok {
-			return nil, fmt.Errorf("post request without content length")
-		}
+	hasLength := request.Headers.Has("Content-Length")
+	hasEncoding := request.Headers.Has("Transfer-Encoding")
+
+	// Si el método es POST, exige Content-Length o Transfer-Encoding
+	if request.Method == "POST" && !hasLength && !hasEncoding {
+		return nil, fmt.Errorf("post request without content length")
 	}
-	
-	// Parsea el cuerpo de la solicitud si Content-Length existe (o body vacío en otro caso)
-	if err := ParseBody(request, reader); err != nil {
-		return nil, err
+
+	// Ambas cabeceras juntas son ambiguas y se rechazan
+	if hasLength && hasEncoding {
+		return nil, fmt.Errorf("both content length and transfer encoding")
 	}
 
 	return request, nil
 }
 
+// Lee el cuerpo de la solicitud, respetando MaxBodyBytes.
+// Devuelve un HttpError 413 si el cuerpo supera el límite.
+func readRequestBody(request *HttpRequest, reader *bufio.Reader, config Config) error {
+	// Parsea el cuerpo de la solicitud si Content-Length o Transfer-Encoding existen (o body vacío en otro caso)
+	return parseBody(request, reader, config)
+}
+
+// Convierte el valor de Content-Length en la longitud del cuerpo. Solo acepta
+// dígitos decimales: un valor como "0x3", "-5" o "3 9" haría leer un cuerpo de
+// otra longitud que la del cliente y el resto se tomaría como la siguiente
+// solicitud de la conexión.
+func parseContentLength(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return 0, fmt.Errorf("bad content length format: %q", value)
+	}
+
+	length, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad content length format: %q", value)
+	}
+	return length, nil
+}
+
+// Lee una línea completa, incluido el '\n' final.
+// Si limit es positivo y la línea lo supera, devuelve un HttpError 431 sin seguir leyendo.
+func readLine(reader *bufio.Reader, limit int) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if limit > 0 && len(line) > limit {
+			return "", NewHttpError(431, "Request Header Fields Too Large", "request header too large")
+		}
+
+		// La línea no cabe en el búfer: sigue leyendo
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+
+		return string(line), err
+	}
+}
+
 // Analiza la parte de las cabeceras de una solicitud HTTP (como string).
 // Devuelve un puntero a HttpRequest (sin el cuerpo) o un error.
 func ParseRequest(headersPart string) (*HttpRequest, error) {
@@ -124,6 +225,10 @@ func ParseRequest(headersPart string) (*HttpRequest, error) {
 		return nil, fmt.Errorf("no method or target")
 	}
 
+	if len(start) < 3 {
+		return nil, fmt.Errorf("no version")
+	}
+
 	// Extrae el método
 	method := start[0]
 
@@ -149,8 +254,8 @@ func ParseRequest(headersPart string) (*HttpRequest, error) {
 		return nil, fmt.Errorf("bad version: %s", version)
 	}
 
-	// Crea un mapa para almacenar las cabeceras
-	headers := make(map[string]string)
+	// Crea las cabeceras; las claves repetidas acumulan sus valores
+	headers := make(Header)
 
 	// Procesa cada línea de cabecera (a partir de la segunda línea)
 	for _, line := range lines[1:] {
@@ -170,39 +275,124 @@ func ParseRequest(headersPart string) (*HttpRequest, error) {
 			continue
 		}
 
-		headers[k] = v
+		headers.Add(k, v)
 	}
 
-	body := ""
+	// Varias Content-Length distintas son ambiguas y se rechazan
+	if lengths := headers.Values("Content-Length"); len(lengths) > 1 {
+		for _, length := range lengths[1:] {
+			if length != lengths[0] {
+				return nil, fmt.Errorf("conflicting content length")
+			}
+		}
+	}
 
 	// Crea y devuelve el objeto HttpRequest con los datos parseados
-	return NewHttpRequest(method, target, headers, body), nil
+	request := &HttpRequest{
+		Method:  method,
+		Target:  target,
+		Version: version,
+		Headers: headers,
+	}
+
+	return request, nil
+}
+
+// Devuelve el identificador de la solicitud: el de la cabecera X-Request-Id
+// si el cliente lo envía, o uno nuevo generado al azar.
+func requestID(request *HttpRequest) string {
+	if id := request.Headers.Get("X-Request-Id"); id != "" {
+		return id
+	}
+
+	buffer := make([]byte, 8)
+	rand.Read(buffer)
+
+	return hex.EncodeToString(buffer)
+}
+
+// Devuelve el valor capturado por el parámetro de ruta con el nombre dado
+// ("*" para el comodín final), o una cadena vacía si no existe.
+func (request *HttpRequest) Param(name string) string {
+	return request.Params[name]
+}
+
+// Indica si la conexión debe mantenerse abierta después de responder.
+// En HTTP/1.1 la conexión es persistente salvo "Connection: close";
+// en HTTP/1.0 solo lo es si el cliente envía "Connection: keep-alive".
+func (request *HttpRequest) KeepAlive() bool {
+	connection := strings.ToLower(strings.Join(request.Headers.Values("Connection"), ","))
+
+	hasToken := func(token string) bool {
+		for _, part := range strings.Split(connection, ",") {
+			if strings.TrimSpace(part) == token {
+				return true
+			}
+		}
+		return false
+	}
+
+	if request.Version == "HTTP/1.1" {
+		return !hasToken("close")
+	}
+
+	return hasToken("keep-alive")
 }
 
 // Parsea el cuerpo de la solicitud HTTP si existe.
+// Acepta cuerpos delimitados por Content-Length o con Transfer-Encoding: chunked.
 func ParseBody(request *HttpRequest, reader *bufio.Reader) error {
+	return parseBody(request, reader, Config{})
+}
+
+// Parsea el cuerpo de la solicitud sin superar los límites de config (0 = sin límite).
+// Un Content-Length mayor que MaxBodyBytes se rechaza antes de reservar memoria.
+func parseBody(request *HttpRequest, reader *bufio.Reader, config Config) error {
+	maxBytes := config.MaxBodyBytes
+
+	// Un cuerpo chunked se decodifica bloque a bloque, incluyendo sus trailers
+	if request.Headers.Has("Transfer-Encoding") {
+		transferEncoding := strings.Join(request.Headers.Values("Transfer-Encoding"), ",")
+		if !isChunked(transferEncoding) {
+			return fmt.Errorf("unsupported transfer encoding: %s", transferEncoding)
+		}
+
+		body, trailers, err := readChunkedBody(reader, config)
+		if err != nil {
+			return err
+		}
+
+		request.Body = body
+		request.Trailers = trailers
+
+		return nil
+	}
+
 	// Comprueba si existe la cabecera Content-Length para leer el cuerpo
-	contentLengthStr, ok := request.Headers["Content-Length"]
-	if !ok {
+	contentLengthStr := request.Headers.Get("Content-Length")
+	if !request.Headers.Has("Content-Length") {
 		return nil
 	}
 
 	// Convierte el valor de Content-Length a entero
-	var contentLength int
-	_, err := fmt.Sscan(contentLengthStr, &contentLength)
+	contentLength, err := parseContentLength(contentLengthStr)
 	if err != nil {
-		return fmt.Errorf("bad content length format: %w", err)
+		return err
 	}
 
 	// Si hay longitud de contenido, lee el cuerpo
-	if contentLength <= 0 {
+	if contentLength == 0 {
 		return nil
 	}
 
+	if maxBytes > 0 && contentLength > maxBytes {
+		return NewHttpError(413, "Content Too Large", "body exceeds %d bytes", maxBytes)
+	}
+
 	body := make([]byte, contentLength)
 
 	// Lee exactamente contentLength bytes desde el reader
-	_, err = io.ReadAtLeast(reader, body, contentLength)
+	_, err = io.ReadFull(reader, body)
 	if err != nil {
 		return fmt.Errorf("can't read body: %w", err)
 	}
@@ -22,7 +22,7 @@ func TestParseRequestSuccess(t *testing.T) {
     if vals.Get("foo") != "bar" {
         t.Errorf("Expected query foo=bar, got %v", vals)
     }
-    if req.Headers["X-Test"] != "OK" || req.Headers["Another"] != "123" {
+    if req.Headers.Get("X-Test") != "OK" || req.Headers.Get("Another") != "123" {
         t.Errorf("Headers parsed incorrectly: %v", req.Headers)
     }
 }
@@ -37,8 +37,8 @@ func TestReadRequestPass(t *testing.T) {
 		t.Errorf("Expected 1 header, not %d", len(request.Headers))
 	}
 
-	if request.Headers["Content-Length"] != "7" {
-		t.Errorf("Expected Content-Length to be 7, not %s", request.Headers["Content-Length"])
+	if request.Headers.Get("Content-Length") != "7" {
+		t.Errorf("Expected Content-Length to be 7, not %s", request.Headers.Get("Content-Length"))
 	}
 
 	if request.Body != "Content" {
@@ -53,6 +53,10 @@ var RejectReadRequestTests = []string{
 	"\r\n\r\n",
 	// bad content length format
 	"GET / HTTP/1.0\r\nContent-Length: A\r\n\r\nContent",
+	"GET / HTTP/1.0\r\nContent-Length: 0x3\r\n\r\nabc",
+	"GET / HTTP/1.0\r\nContent-Length: -5\r\n\r\n",
+	"GET / HTTP/1.0\r\nContent-Length: +3\r\n\r\nabc",
+	"GET / HTTP/1.0\r\nContent-Length: 3 9\r\n\r\nabc",
 	// can't read body
 	"GET / HTTP/1.0\r\nContent-Length: 7\r\n\r\n",
 	// post request without content length
@@ -100,6 +104,8 @@ var RejectParseRequestTests = []string{
 	"HTTP/1.0\r\n\r\n",
 	// no target
 	"GET\r\n\r\n",
+	// no version
+	"GET /\r\n\r\n",
 	// bad target format
 	"GET : HTTP/1.0\r\n\r\n",
 }
@@ -121,3 +127,36 @@ func TestParseRequestReject(t *testing.T) {
 		})
 	}
 }
+
+var KeepAliveTests = []struct {
+	version    string
+	connection string
+	expected   bool
+}{
+	{"HTTP/1.1", "", true},
+	{"HTTP/1.1", "close", false},
+	{"HTTP/1.1", "Keep-Alive, Close", false},
+	{"HTTP/1.0", "", false},
+	{"HTTP/1.0", "keep-alive", true},
+}
+
+func TestKeepAlive(t *testing.T) {
+	for i, test := range KeepAliveTests {
+		t.Run(fmt.Sprintf("TestKeepAlive %d", i), func(t *testing.T) {
+			// Arrange
+			request := NewHttpRequest("GET", nil, map[string]string{}, "")
+			request.Version = test.version
+			if test.connection != "" {
+				request.Headers.Set("Connection", test.connection)
+			}
+
+			// Act
+			act := request.KeepAlive()
+
+			// Assert
+			if act != test.expected {
+				t.Errorf("Expected %v, not %v", test.expected, act)
+			}
+		})
+	}
+}
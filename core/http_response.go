@@ -3,17 +3,26 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net"
-	"sort"
+	"strings"
+	"time"
 )
 
 // Representa una respuesta HTTP.
 type HttpResponse struct {
-	StatusCode int               // Código de estado HTTP (ej. 200, 404).
-	StatusText string            // Texto del estado HTTP (ej. "OK", "Not Found").
-	Headers    map[string]string // Cabeceras HTTP.
-	Body       string            // Cuerpo de la respuesta.
+	Version    string // Versión del protocolo (por defecto HTTP/1.0).
+	StatusCode int    // Código de estado HTTP (ej. 200, 404).
+	StatusText string // Texto del estado HTTP (ej. "OK", "Not Found").
+	Headers    Header // Cabeceras HTTP.
+	Body       string // Cuerpo de la respuesta.
+
+	BodyReader io.Reader // Cuerpo de longitud desconocida; si existe, se envía con codificación chunked.
+
+	stream   func(writer *ResponseWriter) error // Generador del cuerpo por partes (ver Stream).
+	headOnly bool                               // Respuesta a HEAD: se envían las cabeceras sin el cuerpo.
 }
 
 // Crea una nueva instancia de HttpResponse con los valores proporcionados.
@@ -21,7 +30,7 @@ func NewHttpResponse(statusCode int, statusText string, body string) *HttpRespon
 	return &HttpResponse{
 		StatusCode: statusCode,
 		StatusText: statusText,
-		Headers:    make(map[string]string),
+		Headers:    make(Header),
 		Body:       body,
 	}
 }
@@ -41,6 +50,31 @@ func BadRequest() *HttpResponse {
 	return NewHttpResponse(400, "Bad Request", "")
 }
 
+// Crea una respuesta HTTP 204 No Content predeterminada.
+func NoContent() *HttpResponse {
+	return NewHttpResponse(204, "No Content", "")
+}
+
+// Crea una respuesta HTTP 405 Method Not Allowed con la cabecera Allow.
+func MethodNotAllowed(allowed []string) *HttpResponse {
+	return NewHttpResponse(405, "Method Not Allowed", "").SetHeader("Allow", strings.Join(allowed, ", "))
+}
+
+// Crea una respuesta HTTP 503 Service Unavailable con la cabecera Retry-After,
+// que indica al cliente cuántos segundos esperar antes de reintentar.
+func ServiceUnavailable(retryAfter time.Duration) *HttpResponse {
+	return NewHttpResponse(503, "Service Unavailable", "").SetHeader("Retry-After", retryAfterSeconds(retryAfter))
+}
+
+// Convierte la espera sugerida a segundos enteros (al menos 1) para Retry-After.
+func retryAfterSeconds(wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprint(seconds)
+}
+
 // Establece el código de estado de la respuesta.
 func (response *HttpResponse) SetStatusCode(code int) *HttpResponse {
 	response.StatusCode = code
@@ -53,9 +87,15 @@ func (response *HttpResponse) SetStatusText(text string) *HttpResponse {
 	return response
 }
 
-// Establece una cabecera HTTP específica.
+// Establece una cabecera HTTP específica, reemplazando sus valores anteriores.
 func (response *HttpResponse) SetHeader(key, value string) *HttpResponse {
-	response.Headers[key] = value
+	response.Headers.Set(key, value)
+	return response
+}
+
+// Añade un valor a una cabecera HTTP, conservando los anteriores (ej. Set-Cookie).
+func (response *HttpResponse) AddHeader(key, value string) *HttpResponse {
+	response.Headers.Add(key, value)
 	return response
 }
 
@@ -65,6 +105,25 @@ func (response *HttpResponse) SetBody(body string) *HttpResponse {
 	return response
 }
 
+// Establece un cuerpo cuya longitud no se conoce de antemano.
+// El cuerpo se envía en bloques (Transfer-Encoding: chunked) a clientes HTTP/1.1;
+// a clientes HTTP/1.0 se envía sin longitud y la conexión se cierra al terminar.
+// Si el lector implementa io.Closer, se cierra después de enviarlo.
+func (response *HttpResponse) SetBodyReader(reader io.Reader) *HttpResponse {
+	response.BodyReader = reader
+	return response
+}
+
+// Indica si el cuerpo de la respuesta se genera o se lee por partes.
+func (response *HttpResponse) Streaming() bool {
+	return response.BodyReader != nil || response.stream != nil
+}
+
+// Indica si la respuesta se envía con codificación chunked.
+func (response *HttpResponse) Chunked() bool {
+	return response.Streaming() && response.Version == "HTTP/1.1"
+}
+
 // Establece la cabecera Content-Type.
 func (response *HttpResponse) SetContentType(contentType string) *HttpResponse {
 	response.SetHeader("Content-Type", contentType)
@@ -85,31 +144,43 @@ func (response *HttpResponse) Json(json string) *HttpResponse {
 	return response
 }
 
-// Convierte la respuesta HTTP a su representación en formato de cadena HTTP/1.0.
-// Calcula automáticamente la cabecera Content-Length.
+// Convierte la respuesta HTTP a su representación en formato de cadena.
+// Usa la versión de la respuesta (HTTP/1.0 si no se indica) y calcula
+// automáticamente la cabecera Content-Length.
 func (response *HttpResponse) String() string {
 	// Calcula y establece la longitud del contenido.
 	contentLength := len(response.Body)
 	response.SetHeader("Content-Length", fmt.Sprint(contentLength))
 
-	// Formatea las cabeceras.
-	keys := make([]string, 0, len(response.Headers))
-	for key := range response.Headers {
-		keys = append(keys, key)
+	if response.headOnly {
+		return response.head()
 	}
-	sort.Strings(keys)
-	headersStr := ""
-	for _, key := range keys {
-		headersStr += fmt.Sprintf("%s: %s\r\n", key, response.Headers[key])
+
+	return response.head() + response.Body
+}
+
+// Construye la línea de estado y las cabeceras, terminadas por la línea vacía.
+func (response *HttpResponse) head() string {
+	// Formatea las cabeceras; las repetidas se envían en líneas separadas.
+	var headersStr strings.Builder
+	response.Headers.write(&headersStr)
+
+	version := response.Version
+	if version == "" {
+		version = "HTTP/1.0"
 	}
 
-	// Construye la cadena de respuesta HTTP completa.
-	return fmt.Sprintf("HTTP/1.0 %d %s\r\n%s\r\n%s", response.StatusCode, response.StatusText, headersStr, response.Body)
+	// Construye la línea de estado y las cabeceras.
+	return fmt.Sprintf("%s %d %s\r\n%s\r\n", version, response.StatusCode, response.StatusText, headersStr.String())
 }
 
 func (response *HttpResponse) WriteResponse(conn net.Conn) error {
 	slog.Info("Response", "address", conn.RemoteAddr().String(), "status_code", response.StatusCode, "status_text", response.StatusText)
 
+	if response.Streaming() {
+		return response.writeStream(conn)
+	}
+
 	_, err := conn.Write([]byte(response.String()))
 	if err != nil {
 		return err
@@ -119,6 +190,44 @@ func (response *HttpResponse) WriteResponse(conn net.Conn) error {
 
 }
 
+// Envía una respuesta cuyo cuerpo se lee de BodyReader o lo genera un StreamHandle.
+func (response *HttpResponse) writeStream(conn net.Conn) error {
+	writer := newResponseWriter(response, conn)
+
+	// Una respuesta a HEAD solo lleva las cabeceras; el cuerpo no se genera.
+	if response.headOnly {
+		if closer, ok := response.BodyReader.(io.Closer); ok {
+			closer.Close()
+		}
+		return writer.writeHeader()
+	}
+
+	if response.BodyReader != nil {
+		if closer, ok := response.BodyReader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		if _, err := io.Copy(writer, response.BodyReader); err != nil {
+			return err
+		}
+	}
+
+	if response.stream != nil {
+		if err := response.stream(writer); err != nil {
+			// Si aún no se envió nada, todavía se puede responder con un error.
+			if !writer.HeaderWritten() {
+				failed := NewHttpResponse(500, "Internal Server Error", "500 Internal Server Error")
+				failed.Version = response.Version
+				failed.SetHeader("Connection", "close")
+				conn.Write([]byte(failed.String()))
+			}
+			return err
+		}
+	}
+
+	return writer.close()
+}
+
 // JsonObj serializa v a JSON y lo pone en el body con application/json.
 func (r *HttpResponse) JsonObj(v interface{}) *HttpResponse {
 	data, err := json.Marshal(v)
@@ -32,7 +32,7 @@ func TestJsonObjError(t *testing.T) {
     if resp.StatusText != "Internal Server Error" {
         t.Errorf("Expected status text Internal Server Error, got %q", resp.StatusText)
     }
-    if ct := resp.Headers["Content-Type"]; ct != "text/plain" {
+    if ct := resp.Headers.Get("Content-Type"); ct != "text/plain" {
         t.Errorf("Expected text/plain content type, got %q", ct)
     }
     if resp.Body != "json marshal error" {
@@ -1,15 +1,20 @@
 package core
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
-	"sort"
-	"strings"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 // Define el tipo para las funciones que manejan las solicitudes HTTP.
@@ -25,103 +30,164 @@ type Handler struct {
 
 // Representa el servidor HTTP.
 type HttpServer struct {
-	Handlers []Handler    // Lista de manejadores registrados
+	Router   Router       // Enrutador que registra y despacha los manejadores
 	Listener net.Listener // Listener para aceptar conexiones
+	Config   Config       // Configuración del servidor
+
+	middlewares []Middleware   // Middlewares globales, aplicados a todas las solicitudes
+	counters    serverCounters // Métricas del servidor (ver Stats)
+
+	mu         sync.Mutex             // Protege Listener, pool, conns y done
+	pool       *workerPool            // Workers que atienden las conexiones (nil si Config.Workers es 0)
+	conns      map[net.Conn]connState // Conexiones abiertas y su estado (ver trackConn)
+	inShutdown atomic.Bool            // Indica que se inició un Shutdown
+	done       chan struct{}          // Se cierra cuando Shutdown termina
 }
 
-// Crea una nueva instancia de HttpServer.
+// Crea una nueva instancia de HttpServer con la configuración predeterminada.
 func NewHttpServer() *HttpServer {
+	return NewHttpServerWithConfig(DefaultConfig())
+}
+
+// Crea una nueva instancia de HttpServer con la configuración indicada.
+func NewHttpServerWithConfig(config Config) *HttpServer {
 	return &HttpServer{
-		Handlers: []Handler{},
+		Router: NewRouteTable(),
+		Config: config,
 	}
 }
 
 // Agrega un nuevo manejador al servidor.
-func (server *HttpServer) AddHandler(method, path string, handle Handle) {
-	handler := Handler{
-		Method: method,
-		Path:   path,
-		Handle: handle,
-	}
+// Los middlewares indicados se aplican solo a esta ruta (ver Chain).
+func (server *HttpServer) AddHandler(method, path string, handle Handle, middlewares ...Middleware) {
+	server.Router.AddRoute(method, path, Chain(handle, middlewares...))
+}
+
+// Agrega middlewares globales, que envuelven el despacho de todas las solicitudes
+// (incluidas las que terminan en 404 o 405), en el orden en que se agregan.
+func (server *HttpServer) Use(middlewares ...Middleware) {
+	server.middlewares = append(server.middlewares, middlewares...)
+}
 
-	server.Handlers = append(server.Handlers, handler)
+// Devuelve los manejadores registrados en el enrutador del servidor.
+func (server *HttpServer) Routes() []Handler {
+	return server.Router.Routes()
 }
 
 // Un atajo para agregar un manejador para el método GET.
-func (server *HttpServer) Get(path string, handle Handle) {
-	server.AddHandler("GET", path, handle)
+func (server *HttpServer) Get(path string, handle Handle, middlewares ...Middleware) {
+	server.AddHandler("GET", path, handle, middlewares...)
 }
 
 // Un atajo para agregar un manejador para el método POST.
-func (server *HttpServer) Post(path string, handle Handle) {
-	server.AddHandler("POST", path, handle)
+func (server *HttpServer) Post(path string, handle Handle, middlewares ...Middleware) {
+	server.AddHandler("POST", path, handle, middlewares...)
 }
 
 // Un atajo para agregar un manejador para el método DELETE.
-func (server *HttpServer) Delete(path string, handle Handle) {
-	server.AddHandler("DELETE", path, handle)
+func (server *HttpServer) Delete(path string, handle Handle, middlewares ...Middleware) {
+	server.AddHandler("DELETE", path, handle, middlewares...)
 }
 
-// Ordena los manejadores por la especificidad de la ruta (más segmentos primero).
-func (server *HttpServer) SortHandlers() {
-	sort.Slice(server.Handlers, func(i, j int) bool {
-		// Cuenta el número de '/' en cada ruta.
-		iCount := strings.Count(server.Handlers[i].Path, "/")
-		jCount := strings.Count(server.Handlers[j].Path, "/")
+// Verifica si la ruta de la solicitud coincide con la ruta del manejador.
+// Permite coincidencias exactas, de patrón (ver MatchPattern) o de prefijo seguido de '/'.
+func MatchPath(requestPath, handlerPath string) bool {
+	match, _ := MatchPattern(handlerPath, requestPath)
+	return match != NoMatch
+}
 
-		if iCount != jCount {
-			// Ordena de forma descendente por el número de segmentos.
-			return iCount > jCount
-		}
+// Inicia el servidor HTTP en el puerto especificado, en todas las interfaces.
+func (server *HttpServer) Start(port int) error {
+	return server.ListenAndServe("tcp", fmt.Sprintf(":%d", port))
+}
 
-		iLen := len(server.Handlers[i].Path)
-		jLen := len(server.Handlers[j].Path)
+// Inicia el servidor HTTPS en el puerto especificado con el certificado y la clave dados.
+func (server *HttpServer) StartTLS(port int, certFile, keyFile string) error {
+	config, err := LoadTLSConfig(certFile, keyFile, "")
+	if err != nil {
+		return err
+	}
 
-		return iLen > jLen
-	})
+	return server.ListenAndServeTLS("tcp", fmt.Sprintf(":%d", port), config)
 }
 
-// Verifica si la ruta de la solicitud coincide con la ruta del manejador.
-// Permite coincidencias exactas o coincidencias de prefijo si la ruta del manejador termina en '/'.
-func MatchPath(requestPath, handlerPath string) bool {
-	// Coincidencia exacta.
-	if requestPath == handlerPath {
-		return true
+// Escucha en la dirección dada y atiende las conexiones.
+// network es "tcp" (ej. "127.0.0.1:8080" para una interfaz concreta) o "unix"
+// (la ruta de un socket de dominio Unix; un socket anterior en la misma ruta se elimina).
+func (server *HttpServer) ListenAndServe(network, address string) error {
+	ln, err := listen(network, address)
+	if err != nil {
+		return err
 	}
 
-	// Añade '/' al final de la ruta del manejador para comprobar prefijos.
-	handlerPath += "/"
-
-	// Comprueba si la ruta de la solicitud comienza con la ruta del manejador (seguida de '/').
-	return strings.HasPrefix(requestPath, handlerPath)
+	return server.Serve(ln)
 }
 
-// Inicia el servidor HTTP en el puerto especificado.
-func (server *HttpServer) Start(port int) error {
-	// Ordena los manejadores antes de empezar a escuchar.
-	server.SortHandlers()
-
-	// Empieza a escuchar conexiones TCP en el puerto dado.
-	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// Igual que ListenAndServe, pero cifra las conexiones con la configuración TLS dada.
+// Si la configuración exige certificado de cliente (ver LoadTLSConfig), los clientes
+// sin un certificado válido no completan el handshake.
+func (server *HttpServer) ListenAndServeTLS(network, address string, config *tls.Config) error {
+	ln, err := listen(network, address)
 	if err != nil {
 		return err
 	}
 
+	return server.Serve(tls.NewListener(ln, config))
+}
+
+// Crea el listener, eliminando antes un socket Unix abandonado en la misma ruta.
+func listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+
+	return net.Listen(network, address)
+}
+
+// Atiende las conexiones que acepta el listener dado hasta que se cierra.
+// Si Config.HandleSignals está activo, SIGINT o SIGTERM inician un Shutdown con
+// Config.ShutdownTimeout. Tras un Shutdown, Serve no retorna hasta que termina el drenado.
+func (server *HttpServer) Serve(ln net.Listener) error {
 	// Asigna el listener al servidor.
+	server.mu.Lock()
 	server.Listener = ln
+	server.mu.Unlock()
+
+	if server.Config.HandleSignals {
+		// Canal para recibir señales del sistema operativo (SIGINT, SIGTERM).
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+
+		// Goroutine para manejar el cierre ordenado del servidor.
+		go func() {
+			// Espera una señal de interrupción o terminación.
+			if _, ok := <-sigCh; !ok {
+				return
+			}
+			fmt.Println()
 
-	// Canal para recibir señales del sistema operativo (SIGINT, SIGTERM).
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Goroutine para manejar el cierre ordenado del servidor.
-	go func() {
-		// Espera una señal de interrupción o terminación.
-		<-sigCh
-		fmt.Println()
-		// Cierra el listener para detener la aceptación de nuevas conexiones.
-		server.Stop()
-	}()
+			ctx, cancel := server.shutdownContext()
+			defer cancel()
+
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Error("Forced shutdown", "error", err)
+			}
+		}()
+	}
+
+	// Con Workers > 0, un número fijo de goroutines atiende las conexiones.
+	var pool *workerPool
+	if server.Config.Workers > 0 {
+		pool = newWorkerPool(server.Config.Workers, server.Config.QueueSize, server.HandleWithError)
+		defer pool.close()
+
+		server.mu.Lock()
+		server.pool = pool
+		server.mu.Unlock()
+	}
 
 	slog.Info("Server started", "address", ln.Addr().String())
 
@@ -132,6 +198,10 @@ func (server *HttpServer) Start(port int) error {
 
 		// Si el error es porque el listener fue cerrado, termina limpiamente.
 		if errors.Is(err, net.ErrClosed) {
+			// Si el cierre lo inició Shutdown, espera a que se drenen las conexiones.
+			if server.inShutdown.Load() {
+				<-server.drained()
+			}
 			slog.Info("Server stopped")
 			return nil
 		}
@@ -141,14 +211,35 @@ func (server *HttpServer) Start(port int) error {
 			return err
 		}
 
-		// Maneja cada conexión en una goroutine separada.
+		// Sin grupo de workers, maneja cada conexión en una goroutine separada.
 		// HandleWithError se asegura de que los errores se registren.
-		go server.HandleWithError(conn)
+		if pool == nil {
+			go server.HandleWithError(conn)
+			continue
+		}
+
+		// La conexión encolada cuenta para Shutdown aunque aún no tenga worker.
+		server.trackConn(conn, connNew)
+		if !pool.submit(conn) {
+			server.untrackConn(conn)
+			server.reject(pool, conn)
+			continue
+		}
+
+		// Si la conexión tiene que esperar, las conexiones persistentes
+		// inactivas ceden su worker en lugar de retenerlo durante IdleTimeout.
+		if pool.waiting() > 0 {
+			server.evictIdleConns()
+		}
 	}
 }
 
-// Detiene el servidor HTTP.
+// Detiene el servidor HTTP de inmediato: deja de aceptar conexiones sin esperar
+// a las que están en curso. Para un cierre ordenado, usar Shutdown.
 func (server *HttpServer) Stop() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
 	if server.Listener != nil {
 		server.Listener.Close()
 	}
@@ -163,55 +254,235 @@ func (server *HttpServer) HandleWithError(conn net.Conn) {
 }
 
 // Maneja una conexión individual.
+// Atiende solicitudes en bucle mientras la conexión sea persistente
+// (keep-alive), hasta que el cliente la cierre, se agote el tiempo de
+// inactividad o se alcance el máximo de solicitudes por conexión.
 func (server *HttpServer) Handle(conn net.Conn) error {
 	// Asegura que la conexión se cierre al final de la función.
 	defer conn.Close()
 
-	// Lee y parsea la solicitud HTTP de la conexión.
-	request, err := ReadRequest(conn)
-	if err != nil {
-		// En lugar de cerrar sin responder, devolvemos 400 Bad Request con el mensaje de error
-		resp := BadRequest().Text(err.Error())
-		resp.WriteResponse(conn)
-		return nil
+	// Un pánico al escribir la respuesta (por ejemplo, dentro de un Stream) solo
+	// termina esta conexión, nunca el proceso.
+	var request *HttpRequest
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			server.recordPanic(request, recovered)
+		}
+	}()
+
+	server.trackConn(conn, connNew)
+	defer server.untrackConn(conn)
+
+	// En una conexión TLS el handshake debe completarse dentro de ReadHeaderTimeout.
+	var tlsState *tls.ConnectionState
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if server.Config.ReadHeaderTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(server.Config.ReadHeaderTimeout))
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		conn.SetDeadline(time.Time{})
+
+		state := tlsConn.ConnectionState()
+		tlsState = &state
 	}
 
-	slog.Info("Request", "address", conn.RemoteAddr().String(), "method", request.Method, "path", request.Target.Path)
+	reader := bufio.NewReader(conn)
 
-	// Dispatch con detección de método incorrecto
-	var pathMatched bool
-	for _, handler := range server.Handlers {
-		if !MatchPath(request.Target.Path, handler.Path) {
-			continue
+	// Cada escritura de la respuesta dispone como máximo de WriteTimeout.
+	writer := &timeoutConn{Conn: conn, timeout: server.Config.WriteTimeout}
+
+	for served := 0; ; served++ {
+		// Entre solicitudes espera como máximo IdleTimeout a que llegue la siguiente.
+		// La primera solicitud debe llegar completa dentro de ReadHeaderTimeout.
+		if served > 0 {
+			setReadDeadline(conn, server.Config.IdleTimeout)
+		} else {
+			setReadDeadline(conn, server.Config.ReadHeaderTimeout)
 		}
-		// La ruta existe
-		pathMatched = true
-		if request.Method != handler.Method {
-			// Método no soportado en esta ruta
-			continue
+		if _, err := reader.Peek(1); err != nil {
+			// Entre solicitudes, el cliente cerró la conexión, estuvo inactivo
+			// demasiado tiempo o Shutdown cerró la conexión inactiva: se cierra
+			// sin responder. La primera solicitud, en cambio, recibe 408 si no
+			// llegó dentro de ReadHeaderTimeout o 400 si el cliente cerró sin enviarla.
+			if served == 0 && !server.inShutdown.Load() {
+				var netErr net.Error
+				if !errors.As(err, &netErr) || !netErr.Timeout() {
+					err = ErrEmptyRequest
+				}
+				resp := errorResponse(err)
+				resp.SetHeader("Connection", "close")
+				resp.WriteResponse(writer)
+			}
+			return nil
+		}
+
+		// Mientras atiende la solicitud, Shutdown espera en lugar de cerrar la
+		// conexión. Se marca antes de renovar el plazo de lectura, que así no
+		// puede acortar evictIdleConns.
+		server.trackConn(conn, connActive)
+		if served > 0 {
+			setReadDeadline(conn, server.Config.ReadHeaderTimeout)
+		}
+
+		// Lee y parsea la solicitud HTTP de la conexión.
+		var err error
+		request, err = readRequestHead(reader, server.Config)
+		if err == nil {
+			setReadDeadline(conn, server.Config.BodyReadTimeout)
+			err = readRequestBody(request, reader, server.Config)
+		}
+		conn.SetReadDeadline(time.Time{})
+		// Cada solicitud tiene su contexto, con el plazo de X-Request-Deadline si lo trae.
+		var cancel context.CancelFunc
+		if err == nil {
+			request.ctx, cancel, err = requestContext(request)
 		}
-		
-		// Método y ruta coinciden → ejecutar handler
-		resp, err := handler.Handle(request)
 		if err != nil {
-			resp = &HttpResponse{
-				StatusCode: 500,
-				StatusText: "Internal Server Error",
-				Headers:    map[string]string{},
-				Body:       "500 Internal Server Error",
-			}
+			// En lugar de cerrar sin responder, devolvemos 400, 408, 413 o 431 con el mensaje de error
+			resp := errorResponse(err)
+			resp.SetHeader("Connection", "close")
+			resp.WriteResponse(writer)
+			return nil
+		}
+
+		// Decide si la conexión seguirá abierta después de esta respuesta.
+		keepAlive := request.KeepAlive()
+		if max := server.Config.MaxRequestsPerConn; max > 0 && served+1 >= max {
+			keepAlive = false
+		}
+
+		request.ID = requestID(request)
+		request.RemoteAddr = conn.RemoteAddr().String()
+		request.TLS = tlsState
+		request.Sequence = served + 1
+
+		// El contexto se cancela si el cliente cierra la conexión mientras se atiende.
+		stopWatch := watchConn(conn, reader, cancel)
+
+		// La solicitud cuenta como en curso hasta terminar de escribir la respuesta.
+		server.counters.inFlight.Add(1)
+		resp := server.dispatch(request)
+
+		// La respuesta usa la misma versión de protocolo que la solicitud.
+		resp.Version = request.Version
+
+		// La respuesta a HEAD lleva las mismas cabeceras que GET, sin cuerpo.
+		resp.headOnly = request.Method == "HEAD"
+
+		// Un cuerpo de longitud desconocida sin chunked solo termina al cerrar la conexión.
+		if resp.Streaming() && !resp.Chunked() && !resp.headOnly {
+			keepAlive = false
+		}
+
+		// Durante un Shutdown no se aceptan más solicitudes en esta conexión.
+		if server.inShutdown.Load() {
+			keepAlive = false
+		}
+
+		if keepAlive {
+			resp.SetHeader("Connection", "keep-alive")
+		} else {
+			resp.SetHeader("Connection", "close")
+		}
+
+		err = server.writeResponse(resp, writer)
+		stopWatch()
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if !keepAlive {
+			return nil
 		}
-		_ = resp.WriteResponse(conn)
-		return nil
+
+		server.trackConn(conn, connIdle)
 	}
-	
-	if pathMatched {
-		// Ruta conocida + método incorrecto → 400 Bad Request
-		_ = BadRequest().Text("Bad method").WriteResponse(conn)
-		return nil
+}
+
+// Escribe la respuesta de una solicitud en curso y la descuenta al terminar,
+// incluso si un manejador por partes entra en pánico mientras escribe.
+func (server *HttpServer) writeResponse(resp *HttpResponse, writer net.Conn) error {
+	defer server.counters.inFlight.Add(-1)
+	return resp.WriteResponse(writer)
+}
+
+// Establece el plazo de lectura de la conexión; un timeout cero lo elimina.
+func setReadDeadline(conn net.Conn, timeout time.Duration) {
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+	} else {
+		conn.SetReadDeadline(time.Time{})
 	}
-	
-	// Ruta desconocida → 404 Not Found
-	_ = NotFound().Text("404 Not Found").WriteResponse(conn)
-	return nil
-}
\ No newline at end of file
+}
+
+// Conexión que renueva el plazo de escritura antes de cada escritura.
+// Así una respuesta por partes puede durar más que el timeout mientras
+// el cliente siga leyendo, pero un cliente que deja de leer no bloquea
+// la conexión indefinidamente.
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Write(data []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Write(data)
+}
+
+// Despacha la solicitud a través de los middlewares globales y el enrutador.
+// Un error que llegue hasta aquí se convierte en 500 (504 si venció el plazo
+// de la solicitud), y un pánico en un 500 estructurado que incluye el
+// identificador de la solicitud.
+func (server *HttpServer) dispatch(request *HttpRequest) (resp *HttpResponse) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			server.recordPanic(request, recovered)
+			resp = panicResponse(request)
+		}
+	}()
+
+	handle := Chain(server.Router.Route, server.middlewares...)
+
+	resp, err := handle(request)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Venció el plazo de la solicitud: el resultado ya no le sirve al cliente
+		return NewHttpResponse(504, "Gateway Timeout", "").Text("request deadline exceeded")
+	}
+	if err != nil || resp == nil {
+		resp = &HttpResponse{
+			StatusCode: 500,
+			StatusText: "Internal Server Error",
+			Headers:    Header{},
+			Body:       "500 Internal Server Error",
+		}
+	}
+
+	return resp
+}
+
+// Registra un pánico recuperado con su traza y lo suma a las métricas.
+func (server *HttpServer) recordPanic(request *HttpRequest, recovered any) {
+	server.counters.panics.Add(1)
+
+	attrs := []any{"panic", recovered, "stack", string(debug.Stack())}
+	if request != nil {
+		attrs = append(attrs, "request_id", request.ID, "method", request.Method, "path", request.Target.Path)
+	}
+
+	slog.Error("Panic recovered", attrs...)
+}
+
+// Construye la respuesta 500 para una solicitud cuyo manejador entró en pánico.
+func panicResponse(request *HttpRequest) *HttpResponse {
+	resp := NewHttpResponse(500, "Internal Server Error", "").JsonObj(struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}{"internal server error", request.ID})
+
+	return resp.SetHeader("X-Request-Id", request.ID)
+}
@@ -1,10 +1,14 @@
 package core
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"net"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,40 +27,42 @@ func TestGetPost(t *testing.T) {
 	server.Post("/post", handler)
 
 	// Assert
-	if len(server.Handlers) != 2 {
-		t.Fatalf("Expected 2 handlers, not %d", len(server.Handlers))
+	handlers := server.Routes()
+
+	if len(handlers) != 2 {
+		t.Fatalf("Expected 2 handlers, not %d", len(handlers))
 	}
 
-	if server.Handlers[0].Method != "GET" || server.Handlers[0].Path != "/get" {
+	if handlers[0].Method != "GET" || handlers[0].Path != "/get" {
 		t.Errorf("Expected GET handler to be added")
 	}
 
-	if server.Handlers[1].Method != "POST" || server.Handlers[1].Path != "/post" {
+	if handlers[1].Method != "POST" || handlers[1].Path != "/post" {
 		t.Errorf("Expected POST handler to be added")
 	}
 }
 
 func TestSortHandlers(t *testing.T) {
 	// Arrange
-	server := NewHttpServer()
+	table := NewRouteTable()
 
 	handler := func(request *HttpRequest) (*HttpResponse, error) {
 		return Ok(), nil
 	}
 
-	server.AddHandler("GET", "/", handler)
-	server.AddHandler("GET", "/users", handler)
-	server.AddHandler("GET", "/users/name/posts", handler)
-	server.AddHandler("GET", "/users/name", handler)
+	table.AddRoute("GET", "/", handler)
+	table.AddRoute("GET", "/users", handler)
+	table.AddRoute("GET", "/users/name/posts", handler)
+	table.AddRoute("GET", "/users/name", handler)
 
 	expected := []string{"/users/name/posts", "/users/name", "/users", "/"}
 
 	// Act
-	server.SortHandlers()
+	table.SortHandlers()
 
 	// Assert
-	order := make([]string, len(server.Handlers))
-	for i, h := range server.Handlers {
+	order := make([]string, len(table.Routes()))
+	for i, h := range table.Routes() {
 		order[i] = h.Path
 	}
 
@@ -208,3 +214,423 @@ func TestStartUnknownError(t *testing.T) {
 
 	server.Stop()
 }
+
+// Lee una respuesta HTTP completa (línea de estado, cabeceras y cuerpo según Content-Length).
+func readTestResponse(t *testing.T, reader *bufio.Reader) (string, map[string]string, string) {
+	t.Helper()
+
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Error reading status line: %v", err)
+	}
+
+	headers := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading header: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		if line == "" {
+			break
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		headers[parts[0]] = parts[1]
+	}
+
+	length, _ := strconv.Atoi(headers["Content-Length"])
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		t.Fatalf("Error reading body: %v", err)
+	}
+
+	return strings.TrimSuffix(status, "\r\n"), headers, string(body)
+}
+
+func TestHandleKeepAlive(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("pong"), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- server.Handle(conn)
+	}()
+
+	reader := bufio.NewReader(client)
+
+	// Act & Assert: dos solicitudes sobre la misma conexión
+	for i := 0; i < 2; i++ {
+		fmt.Fprint(client, "GET /ping HTTP/1.1\r\nHost: test\r\n\r\n")
+
+		status, headers, body := readTestResponse(t, reader)
+
+		if status != "HTTP/1.1 200 OK" {
+			t.Errorf("Expected status line HTTP/1.1 200 OK, not %s", status)
+		}
+
+		if headers["Connection"] != "keep-alive" {
+			t.Errorf("Expected Connection keep-alive, not %s", headers["Connection"])
+		}
+
+		if body != "pong" {
+			t.Errorf("Expected body pong, not %s", body)
+		}
+	}
+
+	// Connection: close termina el bucle
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	_, headers, _ := readTestResponse(t, reader)
+	if headers["Connection"] != "close" {
+		t.Errorf("Expected Connection close, not %s", headers["Connection"])
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error, %v", err)
+	}
+}
+
+func TestHandleHttp10ClosesByDefault(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("pong"), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	// Act
+	fmt.Fprint(client, "GET /ping HTTP/1.0\r\n\r\n")
+	data, err := io.ReadAll(client)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, %v", err)
+	}
+
+	if !strings.HasPrefix(string(data), "HTTP/1.0 200 OK\r\n") {
+		t.Errorf("Expected HTTP/1.0 status line, not %q", data)
+	}
+
+	if !strings.Contains(string(data), "Connection: close\r\n") {
+		t.Errorf("Expected Connection close, not %q", data)
+	}
+}
+
+func TestHandleMaxRequestsPerConn(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.MaxRequestsPerConn = 2
+	server := NewHttpServerWithConfig(config)
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	reader := bufio.NewReader(client)
+
+	// Act
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\n\r\n")
+	_, first, _ := readTestResponse(t, reader)
+
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\n\r\n")
+	_, second, _ := readTestResponse(t, reader)
+
+	// Assert
+	if first["Connection"] != "keep-alive" {
+		t.Errorf("Expected first response to keep the connection, not %s", first["Connection"])
+	}
+
+	if second["Connection"] != "close" {
+		t.Errorf("Expected second response to close the connection, not %s", second["Connection"])
+	}
+}
+
+func TestHandleIdleTimeout(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.IdleTimeout = 50 * time.Millisecond
+	server := NewHttpServerWithConfig(config)
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- server.Handle(conn)
+	}()
+
+	// Act
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\n\r\n")
+	readTestResponse(t, bufio.NewReader(client))
+
+	// Assert: sin una segunda solicitud, el servidor cierra la conexión
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected no error, %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected idle connection to be closed")
+	}
+}
+
+func TestHandlePanicRecovery(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/panic", func(request *HttpRequest) (*HttpResponse, error) {
+		var matrix [][]int
+		return Ok().Text(fmt.Sprint(matrix[1][0])), nil
+	})
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("pong"), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	reader := bufio.NewReader(client)
+
+	// Act
+	fmt.Fprint(client, "GET /panic HTTP/1.1\r\nX-Request-Id: abc123\r\n\r\n")
+	status, headers, body := readTestResponse(t, reader)
+
+	// Assert: 500 estructurado y la conexión sigue atendiendo solicitudes
+	if status != "HTTP/1.1 500 Internal Server Error" {
+		t.Errorf("Expected 500 status line, not %s", status)
+	}
+
+	if headers["X-Request-Id"] != "abc123" {
+		t.Errorf("Expected X-Request-Id abc123, not %s", headers["X-Request-Id"])
+	}
+
+	expected := `{"error":"internal server error","request_id":"abc123"}`
+	if body != expected {
+		t.Errorf("Expected body %s, not %s", expected, body)
+	}
+
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\n\r\n")
+	_, _, body = readTestResponse(t, reader)
+
+	if body != "pong" {
+		t.Errorf("Expected body pong after panic, not %s", body)
+	}
+
+	if server.Stats().Panics != 1 {
+		t.Errorf("Expected 1 panic, not %d", server.Stats().Panics)
+	}
+}
+
+func TestHandleStreamPanicRecovery(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		writer.WriteString("partial")
+		writer.Flush()
+		panic("boom")
+	}))
+
+	// Act
+	roundTrip(t, server, "GET /stream HTTP/1.1\r\n\r\n")
+
+	// Assert: el pánico cierra la conexión sin terminar el proceso
+	if server.Stats().Panics != 1 {
+		t.Errorf("Expected 1 panic, not %d", server.Stats().Panics)
+	}
+}
+
+func TestHandleLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(config *Config)
+		request string
+		status  string
+	}{
+		{
+			name:    "header bytes",
+			config:  func(config *Config) { config.MaxHeaderBytes = 64 },
+			request: "GET /ping HTTP/1.1\r\nX-Long: " + strings.Repeat("a", 128) + "\r\n\r\n",
+			status:  "431 Request Header Fields Too Large",
+		},
+		{
+			name:    "header count",
+			config:  func(config *Config) { config.MaxHeaderCount = 2 },
+			request: "GET /ping HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n",
+			status:  "431 Request Header Fields Too Large",
+		},
+		{
+			name:    "content length",
+			config:  func(config *Config) { config.MaxBodyBytes = 4 },
+			request: "POST /ping HTTP/1.1\r\nContent-Length: 10\r\n\r\n0123456789",
+			status:  "413 Content Too Large",
+		},
+		{
+			name:    "chunked body",
+			config:  func(config *Config) { config.MaxBodyBytes = 4 },
+			request: "POST /ping HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n3\r\ndef\r\n0\r\n\r\n",
+			status:  "413 Content Too Large",
+		},
+		{
+			// 1 + 0x7fffffffffffffff desborda int64 si se suma al tamaño leído
+			name:    "huge second chunk",
+			config:  func(config *Config) { config.MaxBodyBytes = 4 },
+			request: "POST /ping HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1\r\na\r\n7fffffffffffffff\r\n" + strings.Repeat("b", 64),
+			status:  "413 Content Too Large",
+		},
+		{
+			name:    "chunk size line",
+			config:  func(config *Config) {},
+			request: "POST /ping HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3;" + strings.Repeat("x", maxChunkLineBytes) + "\r\nabc\r\n0\r\n\r\n",
+			status:  "413 Content Too Large",
+		},
+		{
+			name:    "trailer bytes",
+			config:  func(config *Config) { config.MaxHeaderBytes = 64 },
+			request: "POST /ping HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\nX-Long: " + strings.Repeat("a", 128) + "\r\n\r\n",
+			status:  "431 Request Header Fields Too Large",
+		},
+		{
+			name:    "trailer count",
+			config:  func(config *Config) { config.MaxHeaderCount = 2 },
+			request: "POST /ping HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n",
+			status:  "431 Request Header Fields Too Large",
+		},
+		{
+			// El resto de la conexión no debe leerse como otra solicitud
+			name:    "lenient content length",
+			config:  func(config *Config) {},
+			request: "POST /ping HTTP/1.1\r\nContent-Length: 3 9\r\n\r\nabcGET /ping HTTP/1.1\r\n\r\n",
+			status:  "400 Bad Request",
+		},
+		{
+			name:    "body within limit",
+			config:  func(config *Config) { config.MaxBodyBytes = 4 },
+			request: "POST /ping HTTP/1.1\r\nContent-Length: 4\r\nConnection: close\r\n\r\n0123",
+			status:  "200 OK",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			config := DefaultConfig()
+			test.config(&config)
+			server := NewHttpServerWithConfig(config)
+			server.Post("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+				return Ok(), nil
+			})
+			server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+				return Ok(), nil
+			})
+
+			client, conn := net.Pipe()
+			defer client.Close()
+			go server.Handle(conn)
+
+			// Act: el servidor puede responder antes de leer toda la solicitud
+			go fmt.Fprint(client, test.request)
+			status, headers, _ := readTestResponse(t, bufio.NewReader(client))
+
+			// Assert
+			// Los errores de lectura se responden antes de conocer la versión del cliente
+			if !strings.HasSuffix(status, " "+test.status) {
+				t.Errorf("Expected %q, got %q", test.status, status)
+			}
+			if headers["Connection"] != "close" {
+				t.Errorf("Expected Connection: close, got %q", headers["Connection"])
+			}
+		})
+	}
+}
+
+func TestHandleReadTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(config *Config)
+		request string
+	}{
+		{
+			name:    "headers",
+			config:  func(config *Config) { config.ReadHeaderTimeout = 50 * time.Millisecond },
+			request: "GET /ping HTTP/1.1\r\nX-Slow: ",
+		},
+		{
+			name:    "no request",
+			config:  func(config *Config) { config.ReadHeaderTimeout = 50 * time.Millisecond },
+			request: "",
+		},
+		{
+			name:    "body",
+			config:  func(config *Config) { config.BodyReadTimeout = 50 * time.Millisecond },
+			request: "POST /ping HTTP/1.1\r\nContent-Length: 10\r\n\r\n01234",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			config := DefaultConfig()
+			test.config(&config)
+			server := NewHttpServerWithConfig(config)
+			server.Post("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+				return Ok(), nil
+			})
+
+			client, conn := net.Pipe()
+			defer client.Close()
+			go server.Handle(conn)
+
+			// Act: la solicitud queda incompleta (o ni siquiera empieza)
+			if test.request != "" {
+				fmt.Fprint(client, test.request)
+			}
+			status, _, _ := readTestResponse(t, bufio.NewReader(client))
+
+			// Assert
+			if !strings.HasSuffix(status, " 408 Request Timeout") {
+				t.Errorf("Expected 408, got %q", status)
+			}
+		})
+	}
+}
+
+func TestHandleCountsInFlightRequests(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+
+	var during int64
+	server.Get("/busy", func(request *HttpRequest) (*HttpResponse, error) {
+		during = server.Stats().InFlight
+		return Ok(), nil
+	})
+
+	// Act
+	roundTrip(t, server, "GET /busy HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	// Assert: la solicitud cuenta mientras se atiende y deja de contar al responder
+	if during != 1 {
+		t.Errorf("Expected 1 in-flight request during handling, not %d", during)
+	}
+
+	if server.Stats().InFlight != 0 {
+		t.Errorf("Expected 0 in-flight requests after response, not %d", server.Stats().InFlight)
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Solicitud con Idempotency-Key vista por el middleware Idempotency.
+type idempotentEntry struct {
+	body     [sha256.Size]byte // Huella del cuerpo de la primera solicitud
+	response *HttpResponse     // Respuesta guardada; nil mientras se atiende la primera
+	expires  time.Time
+}
+
+// Middleware que deduplica las solicitudes con la cabecera Idempotency-Key.
+// La respuesta a la primera solicitud se guarda durante ttl y se repite a las
+// siguientes con la misma clave, método y ruta sin volver a llamar al manejador.
+// Mientras la primera se atiende, las repetidas reciben 409 Conflict; si la
+// clave llega con otro cuerpo, 422. Los errores, las respuestas 5xx y las de
+// cuerpo incremental (Stream o BodyReader) no se guardan, así que una repetición
+// vuelve a llamar al manejador. Las solicitudes sin la cabecera pasan sin más.
+// Uso: server.Use(core.Idempotency(10 * time.Minute))
+func Idempotency(ttl time.Duration) Middleware {
+	var mu sync.Mutex
+	entries := make(map[string]*idempotentEntry)
+	var nextSweep time.Time
+
+	return func(next Handle) Handle {
+		return func(request *HttpRequest) (*HttpResponse, error) {
+			key := request.Headers.Get("Idempotency-Key")
+			if key == "" {
+				return next(request)
+			}
+			id := request.Method + " " + request.Target.Path + " " + key
+			body := sha256.Sum256([]byte(request.Body))
+			now := time.Now()
+
+			mu.Lock()
+			// Las claves vencidas se eliminan como mucho una vez por ttl
+			if now.After(nextSweep) {
+				for stored, entry := range entries {
+					if entry.response != nil && now.After(entry.expires) {
+						delete(entries, stored)
+					}
+				}
+				nextSweep = now.Add(ttl)
+			}
+
+			entry, seen := entries[id]
+			if seen && entry.response != nil && now.After(entry.expires) {
+				seen = false
+			}
+			if seen {
+				mu.Unlock()
+				switch {
+				case entry.body != body:
+					return NewHttpResponse(422, "Unprocessable Entity", "").Text("idempotency key reused with a different body"), nil
+				case entry.response == nil:
+					return NewHttpResponse(409, "Conflict", "").Text("request with this idempotency key in progress"), nil
+				}
+				return entry.response.clone(), nil
+			}
+			entry = &idempotentEntry{body: body}
+			entries[id] = entry
+			mu.Unlock()
+
+			response, err := next(request)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || response == nil || response.StatusCode >= 500 || response.stream != nil || response.BodyReader != nil {
+				delete(entries, id)
+				return response, err
+			}
+			entry.response = response.clone()
+			entry.expires = time.Now().Add(ttl)
+			return response, nil
+		}
+	}
+}
+
+// Devuelve una copia de la respuesta con cabeceras independientes.
+func (response *HttpResponse) clone() *HttpResponse {
+	clone := *response
+	clone.Headers = response.Headers.Clone()
+	return &clone
+}
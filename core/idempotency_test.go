@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func idempotentRequest(key, body string) *HttpRequest {
+	target, _ := url.Parse("/createfile")
+	return NewHttpRequest("POST", target, map[string]string{"Idempotency-Key": key}, body)
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	// Arrange
+	calls := 0
+	handle := Idempotency(time.Minute)(func(request *HttpRequest) (*HttpResponse, error) {
+		calls++
+		return Ok().Text(fmt.Sprintf("call %d", calls)), nil
+	})
+
+	// Act
+	first, _ := handle(idempotentRequest("k1", "data"))
+	first.Headers.Set("Connection", "close")
+	repeated, _ := handle(idempotentRequest("k1", "data"))
+	other, _ := handle(idempotentRequest("k2", "data"))
+	reused, _ := handle(idempotentRequest("k1", "other data"))
+
+	// Assert
+	if calls != 2 {
+		t.Errorf("Expected 2 handler calls, not %d", calls)
+	}
+	if repeated.Body != "call 1" || repeated.Headers.Has("Connection") {
+		t.Errorf("Expected an untouched copy of the first response, not %q with %v", repeated.Body, repeated.Headers)
+	}
+	if other.Body != "call 2" {
+		t.Errorf("Expected a new key to reach the handler, not %q", other.Body)
+	}
+	if reused.StatusCode != 422 {
+		t.Errorf("Expected 422 for a key reused with another body, not %d", reused.StatusCode)
+	}
+}
+
+func TestIdempotencyInProgressAndFailures(t *testing.T) {
+	// Arrange
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	handle := Idempotency(time.Minute)(blockingHandle(started, release, "slow"))
+
+	done := make(chan *HttpResponse)
+	go func() {
+		response, _ := handle(idempotentRequest("k1", ""))
+		done <- response
+	}()
+	<-started
+
+	failures := 0
+	failing := Idempotency(time.Minute)(func(request *HttpRequest) (*HttpResponse, error) {
+		failures++
+		return NewHttpResponse(500, "Internal Server Error", ""), nil
+	})
+
+	// Act
+	conflict, _ := handle(idempotentRequest("k1", ""))
+	close(release)
+	<-done
+	failing(idempotentRequest("k1", ""))
+	failing(idempotentRequest("k1", ""))
+
+	// Assert
+	if conflict.StatusCode != 409 {
+		t.Errorf("Expected 409 while the first request runs, not %d", conflict.StatusCode)
+	}
+	if failures != 2 {
+		t.Errorf("Expected a failed request to reach the handler again, not %d calls", failures)
+	}
+}
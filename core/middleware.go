@@ -0,0 +1,37 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Define el tipo para las funciones que envuelven un Handle.
+// Un middleware recibe el siguiente Handle de la cadena y devuelve uno nuevo que
+// puede actuar antes y después de llamarlo, o responder sin llamarlo.
+type Middleware func(next Handle) Handle
+
+// Aplica los middlewares a un Handle.
+// El primer middleware es el más externo: es el primero en ver la solicitud
+// y el último en ver la respuesta.
+func Chain(handle Handle, middlewares ...Middleware) Handle {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handle = middlewares[i](handle)
+	}
+	return handle
+}
+
+// Middleware que registra cada solicitud y el resultado de atenderla.
+func Logger(next Handle) Handle {
+	return func(request *HttpRequest) (*HttpResponse, error) {
+		slog.Info("Request", "address", request.RemoteAddr, "method", request.Method, "path", request.Target.Path, "request_id", request.ID)
+
+		start := time.Now()
+		response, err := next(request)
+
+		if err != nil {
+			slog.Error("Handler error", "method", request.Method, "path", request.Target.Path, "request_id", request.ID, "error", err, "duration", time.Since(start))
+		}
+
+		return response, err
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Middleware de prueba que anota su nombre antes y después del siguiente Handle.
+func tracing(name string, trace *[]string) Middleware {
+	return func(next Handle) Handle {
+		return func(request *HttpRequest) (*HttpResponse, error) {
+			*trace = append(*trace, name+" before")
+			response, err := next(request)
+			*trace = append(*trace, name+" after")
+			return response, err
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	// Arrange
+	trace := []string{}
+	handle := func(request *HttpRequest) (*HttpResponse, error) {
+		trace = append(trace, "handle")
+		return Ok(), nil
+	}
+
+	target, _ := url.Parse("/")
+
+	// Act
+	Chain(handle, tracing("a", &trace), tracing("b", &trace))(NewHttpRequest("GET", target, map[string]string{}, ""))
+
+	// Assert
+	expected := []string{"a before", "b before", "handle", "b after", "a after"}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("Expected %v, not %v", expected, trace)
+	}
+}
+
+func TestServerMiddlewares(t *testing.T) {
+	// Arrange
+	trace := []string{}
+	server := NewHttpServer()
+	server.Use(tracing("global", &trace))
+
+	server.Get("/open", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("open"), nil
+	})
+
+	// Middleware por ruta que rechaza solicitudes sin la cabecera X-Token
+	auth := func(next Handle) Handle {
+		return func(request *HttpRequest) (*HttpResponse, error) {
+			if request.Headers.Get("X-Token") != "secret" {
+				return NewHttpResponse(401, "Unauthorized", ""), nil
+			}
+			return next(request)
+		}
+	}
+	server.Get("/private", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("private"), nil
+	}, auth)
+
+	tests := []struct {
+		request string
+		status  string
+	}{
+		{"GET /open HTTP/1.0\r\n\r\n", "HTTP/1.0 200 OK"},
+		{"GET /private HTTP/1.0\r\n\r\n", "HTTP/1.0 401 Unauthorized"},
+		{"GET /private HTTP/1.0\r\nX-Token: secret\r\n\r\n", "HTTP/1.0 200 OK"},
+		{"GET /missing HTTP/1.0\r\n\r\n", "HTTP/1.0 404 Not Found"},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestServerMiddlewares %d", i), func(t *testing.T) {
+			// Act
+			response := roundTrip(t, server, test.request)
+
+			// Assert
+			status, _, _ := strings.Cut(response, "\r\n")
+			if status != test.status {
+				t.Errorf("Expected %q, not %q", test.status, status)
+			}
+		})
+	}
+
+	// El middleware global envuelve todas las solicitudes, incluidas las 404
+	if len(trace) != 2*len(tests) {
+		t.Errorf("Expected global middleware to run %d times, not %d", len(tests), len(trace)/2)
+	}
+}
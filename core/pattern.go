@@ -0,0 +1,217 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// Resultado de comparar la ruta de una solicitud con el patrón de un manejador.
+type Match int
+
+const (
+	NoMatch     Match = iota // La ruta no corresponde al patrón
+	PrefixMatch              // La ruta empieza con el patrón seguido de '/'
+	ExactMatch               // La ruta corresponde completamente al patrón (incluyendo comodines)
+)
+
+// Tipos de segmento de un patrón, de menor a mayor especificidad.
+const (
+	wildcardSegment = iota // "*" al final del patrón: captura el resto de la ruta
+	paramSegment           // "{nombre}": captura un segmento
+	staticSegment          // texto literal
+)
+
+// Divide una ruta en segmentos, ignorando la '/' inicial.
+// La ruta raíz "/" no tiene segmentos.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Divide un patrón en segmentos.
+// Un patrón terminado en '/' (excepto la raíz) equivale al mismo patrón terminado en "/*".
+func splitPattern(pattern string) []string {
+	if pattern != "/" && strings.HasSuffix(pattern, "/") {
+		pattern += "*"
+	}
+	return splitPath(pattern)
+}
+
+// Devuelve el tipo de un segmento de patrón.
+func segmentKind(segment string) int {
+	if segment == "*" {
+		return wildcardSegment
+	}
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return paramSegment
+	}
+	return staticSegment
+}
+
+// Compara la ruta de una solicitud con un patrón y devuelve los valores capturados.
+//
+// Un patrón se compone de segmentos separados por '/':
+//   - texto literal, que debe coincidir exactamente ("/files");
+//   - parámetros "{nombre}", que capturan un segmento no vacío ("/files/{name}");
+//   - un comodín "*" final, que captura el resto de la ruta bajo la clave "*" ("/static/*").
+//
+// Un patrón literal también corresponde, como prefijo, a las rutas que continúan
+// tras una '/' ("/users" corresponde a "/users/name"), salvo la raíz "/", que solo
+// corresponde a sí misma. Un patrón con parámetros no: cada parámetro captura un
+// único segmento, y las rutas más profundas solo corresponden con un "/*" final
+// ("/files/{name}" no corresponde a "/files/a/b").
+func MatchPattern(pattern, path string) (Match, map[string]string) {
+	patternSegments := splitPattern(pattern)
+	pathSegments := splitPath(path)
+	params := make(map[string]string)
+
+	for i, segment := range patternSegments {
+		if i >= len(pathSegments) {
+			return NoMatch, nil
+		}
+
+		switch segmentKind(segment) {
+		case wildcardSegment:
+			if i == len(patternSegments)-1 {
+				params["*"] = strings.Join(pathSegments[i:], "/")
+				return ExactMatch, params
+			}
+			// Un '*' en medio del patrón es un segmento literal
+			if pathSegments[i] != segment {
+				return NoMatch, nil
+			}
+		case paramSegment:
+			if pathSegments[i] == "" {
+				return NoMatch, nil
+			}
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+		default:
+			if pathSegments[i] != segment {
+				return NoMatch, nil
+			}
+		}
+	}
+
+	if len(pathSegments) == len(patternSegments) {
+		return ExactMatch, params
+	}
+
+	// La raíz no funciona como prefijo; de lo contrario correspondería a todas las rutas.
+	// Un patrón con parámetros tampoco: el resto de la ruta no pertenece a ningún parámetro.
+	if len(patternSegments) == 0 || len(params) > 0 {
+		return NoMatch, nil
+	}
+
+	return PrefixMatch, params
+}
+
+// Indica si el patrón a es más específico que el patrón b.
+// Compara segmento a segmento: un literal precede a un parámetro y un parámetro
+// a un comodín. Si un patrón es prefijo del otro, precede el de más segmentos.
+// Los empates se resuelven por longitud (mayor primero) y luego alfabéticamente,
+// de modo que el orden sea siempre determinista.
+func MoreSpecific(a, b string) bool {
+	aSegments := splitPattern(a)
+	bSegments := splitPattern(b)
+
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		aKind := segmentKind(aSegments[i])
+		bKind := segmentKind(bSegments[i])
+
+		if aKind != bKind {
+			return aKind > bKind
+		}
+	}
+
+	if len(aSegments) != len(bSegments) {
+		return len(aSegments) > len(bSegments)
+	}
+
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+
+	return a < b
+}
+
+// Ordena los manejadores del más específico al menos específico.
+func SortHandlers(handlers []Handler) {
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return MoreSpecific(handlers[i].Path, handlers[j].Path)
+	})
+}
+
+// Busca el manejador que debe atender la solicitud con el método y la ruta dados.
+// Entre los manejadores del método, prefiere una coincidencia exacta a una de prefijo
+// y, a igualdad, el patrón más específico; el resultado no depende del orden de registro.
+// Devuelve el manejador (o nil), los parámetros capturados y si algún manejador,
+// de cualquier método, corresponde a la ruta.
+func FindHandler(handlers []Handler, method, path string) (*Handler, map[string]string, bool) {
+	var (
+		best        *Handler
+		bestMatch   Match
+		bestParams  map[string]string
+		pathMatched bool
+	)
+
+	for i := range handlers {
+		handler := &handlers[i]
+
+		match, params := MatchPattern(handler.Path, path)
+		if match == NoMatch {
+			continue
+		}
+
+		// La ruta existe
+		pathMatched = true
+
+		if handler.Method != method {
+			// Método no soportado en esta ruta
+			continue
+		}
+
+		if best == nil || match > bestMatch || (match == bestMatch && MoreSpecific(handler.Path, best.Path)) {
+			best, bestMatch, bestParams = handler, match, params
+		}
+	}
+
+	return best, bestParams, pathMatched
+}
+
+// Devuelve los métodos permitidos para una ruta, ordenados alfabéticamente.
+// Incluye HEAD si hay un manejador GET y siempre OPTIONS, que el enrutador
+// responde automáticamente. La ruta "*" reúne los métodos de todos los manejadores.
+// Devuelve nil si ningún manejador corresponde a la ruta.
+func AllowedMethods(handlers []Handler, path string) []string {
+	methods := make(map[string]bool)
+
+	for _, handler := range handlers {
+		if path != "*" {
+			if match, _ := MatchPattern(handler.Path, path); match == NoMatch {
+				continue
+			}
+		}
+
+		methods[handler.Method] = true
+	}
+
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if methods["GET"] {
+		methods["HEAD"] = true
+	}
+	methods["OPTIONS"] = true
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	return allowed
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var MatchPatternTests = []struct {
+	pattern  string
+	path     string
+	expected Match
+	params   map[string]string
+}{
+	{"/", "/", ExactMatch, map[string]string{}},
+	{"/", "/users", NoMatch, nil},
+	{"/users", "/users", ExactMatch, map[string]string{}},
+	{"/users", "/users/name", PrefixMatch, map[string]string{}},
+	{"/users", "/usersx", NoMatch, nil},
+	{"/files/{name}", "/files/report.txt", ExactMatch, map[string]string{"name": "report.txt"}},
+	{"/files/{name}", "/files/", NoMatch, nil},
+	{"/files/{name}", "/files", NoMatch, nil},
+	{"/jobs/{id}/result", "/jobs/42/result", ExactMatch, map[string]string{"id": "42"}},
+	{"/jobs/{id}/result", "/jobs/42/status", NoMatch, nil},
+	{"/jobs/{id}", "/jobs/42/result", NoMatch, nil},
+	{"/files/{name}", "/files/a/b", NoMatch, nil},
+	{"/jobs/{id}/*", "/jobs/42/result/raw", ExactMatch, map[string]string{"id": "42", "*": "result/raw"}},
+	{"/static/*", "/static/css/site.css", ExactMatch, map[string]string{"*": "css/site.css"}},
+	{"/static/*", "/static/", ExactMatch, map[string]string{"*": ""}},
+	{"/static/*", "/static", NoMatch, nil},
+	{"/api/", "/api/v1", ExactMatch, map[string]string{"*": "v1"}},
+	{"/a/", "/a", NoMatch, nil},
+}
+
+func TestMatchPattern(t *testing.T) {
+	for i, test := range MatchPatternTests {
+		t.Run(fmt.Sprintf("TestMatchPattern %d", i), func(t *testing.T) {
+			// Act
+			match, params := MatchPattern(test.pattern, test.path)
+
+			// Assert
+			if match != test.expected {
+				t.Errorf("MatchPattern(%q, %q): expected %v, not %v", test.pattern, test.path, test.expected, match)
+			}
+
+			if !reflect.DeepEqual(params, test.params) {
+				t.Errorf("MatchPattern(%q, %q): expected params %v, not %v", test.pattern, test.path, test.params, params)
+			}
+		})
+	}
+}
+
+func TestSortHandlersPrecedence(t *testing.T) {
+	// Arrange
+	handle := func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	}
+
+	handlers := []Handler{
+		{Method: "GET", Path: "/files/*", Handle: handle},
+		{Method: "GET", Path: "/", Handle: handle},
+		{Method: "GET", Path: "/files/{name}", Handle: handle},
+		{Method: "GET", Path: "/files/readme", Handle: handle},
+		{Method: "GET", Path: "/files", Handle: handle},
+		{Method: "GET", Path: "/files/{name}/meta", Handle: handle},
+	}
+
+	expected := []string{"/files/readme", "/files/{name}/meta", "/files/{name}", "/files/*", "/files", "/"}
+
+	// Act
+	SortHandlers(handlers)
+
+	// Assert
+	order := make([]string, len(handlers))
+	for i, h := range handlers {
+		order[i] = h.Path
+	}
+
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected handlers to be sorted as %v, not %v", expected, order)
+	}
+}
+
+var FindHandlerTests = []struct {
+	method   string
+	path     string
+	expected string
+	params   map[string]string
+}{
+	{"GET", "/files/readme", "/files/readme", map[string]string{}},
+	{"GET", "/files/report", "/files/{name}", map[string]string{"name": "report"}},
+	{"GET", "/files/a/b", "/files/*", map[string]string{"*": "a/b"}},
+	{"GET", "/files", "/files", map[string]string{}},
+	{"DELETE", "/files/report", "/files/{name}", map[string]string{"name": "report"}},
+	{"DELETE", "/files/readme", "/files/{name}", map[string]string{"name": "readme"}},
+}
+
+func TestFindHandler(t *testing.T) {
+	// Arrange: el orden de registro no debe influir en el resultado
+	handle := func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	}
+
+	handlers := []Handler{
+		{Method: "GET", Path: "/files", Handle: handle},
+		{Method: "GET", Path: "/files/*", Handle: handle},
+		{Method: "GET", Path: "/files/{name}", Handle: handle},
+		{Method: "GET", Path: "/files/readme", Handle: handle},
+		{Method: "DELETE", Path: "/files/{name}", Handle: handle},
+	}
+
+	for i, test := range FindHandlerTests {
+		t.Run(fmt.Sprintf("TestFindHandler %d", i), func(t *testing.T) {
+			// Act
+			handler, params, pathMatched := FindHandler(handlers, test.method, test.path)
+
+			// Assert
+			if handler == nil {
+				t.Fatalf("Expected a handler for %s %s", test.method, test.path)
+			}
+
+			if handler.Path != test.expected {
+				t.Errorf("Expected handler %s, not %s", test.expected, handler.Path)
+			}
+
+			if !reflect.DeepEqual(params, test.params) {
+				t.Errorf("Expected params %v, not %v", test.params, params)
+			}
+
+			if !pathMatched {
+				t.Errorf("Expected path to be matched")
+			}
+		})
+	}
+}
+
+func TestFindHandlerMethodMismatch(t *testing.T) {
+	// Arrange
+	handlers := []Handler{
+		{Method: "POST", Path: "/files/{name}", Handle: nil},
+	}
+
+	// Act
+	handler, _, pathMatched := FindHandler(handlers, "GET", "/files/report")
+
+	// Assert
+	if handler != nil {
+		t.Errorf("Expected no handler, not %v", handler.Path)
+	}
+
+	if !pathMatched {
+		t.Errorf("Expected path to be matched")
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Grupo de goroutines de tamaño fijo que atienden las conexiones aceptadas.
+// Las conexiones esperan en una cola acotada; si la cola está llena, el
+// servidor rechaza la conexión con un número acotado de goroutines (ver reject).
+//
+// Cada conexión ocupa un worker mientras está abierta, también entre
+// solicitudes de una conexión persistente. Para que las conexiones inactivas no
+// dejen sin workers a las nuevas, cuando una conexión tiene que esperar en la
+// cola el servidor cierra las conexiones persistentes inactivas (evictIdleConns).
+type workerPool struct {
+	queue  chan net.Conn  // Conexiones aceptadas que esperan un worker
+	busy   atomic.Int64   // Workers atendiendo una conexión
+	size   int            // Número de workers
+	handle func(net.Conn) // Función que atiende cada conexión
+	wg     sync.WaitGroup // Espera a que terminen los workers
+
+	rejecting chan struct{} // Rechazos en curso, como mucho rejectSlots
+}
+
+// Crea el grupo y arranca sus workers.
+func newWorkerPool(size, queueSize int, handle func(net.Conn)) *workerPool {
+	pool := &workerPool{
+		queue:     make(chan net.Conn, queueSize),
+		size:      size,
+		handle:    handle,
+		rejecting: make(chan struct{}, rejectSlots),
+	}
+
+	pool.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Atiende conexiones de la cola hasta que se cierra.
+func (pool *workerPool) work() {
+	defer pool.wg.Done()
+
+	for conn := range pool.queue {
+		pool.busy.Add(1)
+		pool.handle(conn)
+		pool.busy.Add(-1)
+	}
+}
+
+// Encola una conexión sin bloquear. Devuelve false si la cola está llena.
+func (pool *workerPool) submit(conn net.Conn) bool {
+	select {
+	case pool.queue <- conn:
+		return true
+	default:
+		return false
+	}
+}
+
+// Devuelve cuántas conexiones esperan un worker.
+func (pool *workerPool) waiting() int {
+	return len(pool.queue)
+}
+
+// Cierra la cola; los workers terminan después de atender lo ya encolado.
+func (pool *workerPool) close() {
+	close(pool.queue)
+}
+
+// Plazo para rechazar una conexión: escribir el 503 y descartar la solicitud.
+const rejectTimeout = 100 * time.Millisecond
+
+// Bytes de la solicitud que se descartan como máximo al rechazar una conexión.
+const rejectDrainBytes = 64 << 10
+
+// Rechazos que pueden estar en curso a la vez.
+const rejectSlots = 16
+
+// Rechaza una conexión que no cabe en la cola sin bloquear el bucle de
+// aceptación: el 503 se envía desde una de las rejectSlots goroutines de
+// rechazo. Si todas están ocupadas (por ejemplo, con clientes que no envían
+// nada), la conexión se cierra sin respuesta.
+func (server *HttpServer) reject(pool *workerPool, conn net.Conn) {
+	server.counters.rejected.Add(1)
+
+	select {
+	case pool.rejecting <- struct{}{}:
+		go func() {
+			defer func() { <-pool.rejecting }()
+			server.writeRejection(conn)
+		}()
+	default:
+		conn.Close()
+	}
+}
+
+// Responde 503 a una conexión rechazada y la cierra. Tras responder cierra su
+// sentido de escritura y descarta lo que envíe el cliente hasta que cierre,
+// con un límite de bytes y de tiempo: cerrar con datos sin leer provocaría un
+// RST y el cliente podría no llegar a leer el 503.
+func (server *HttpServer) writeRejection(conn net.Conn) {
+	defer conn.Close()
+
+	resp := ServiceUnavailable(server.Config.RetryAfter).Text("server busy")
+	resp.SetHeader("Connection", "close")
+
+	conn.SetDeadline(time.Now().Add(rejectTimeout))
+	if err := resp.WriteResponse(conn); err != nil {
+		return
+	}
+
+	if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+		closer.CloseWrite()
+	}
+	io.CopyN(io.Discard, conn, rejectDrainBytes)
+}
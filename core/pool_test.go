@@ -0,0 +1,205 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+)
+
+// Conecta con el servidor de pruebas, reintentando mientras arranca.
+func dialServer(t *testing.T, port int) net.Conn {
+	t.Helper()
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		conn, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+		if err == nil {
+			return conn
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Error: %v", err)
+	return nil
+}
+
+func TestWorkerPoolRejectsWhenQueueFull(t *testing.T) {
+	// Arrange: un worker y una cola de una conexión
+	config := DefaultConfig()
+	config.HandleSignals = false
+	config.Workers = 1
+	config.QueueSize = 1
+	config.RetryAfter = 2 * time.Second
+	server := NewHttpServerWithConfig(config)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server.Get("/slow", func(request *HttpRequest) (*HttpResponse, error) {
+		started <- struct{}{}
+		<-release
+		return Ok().Text("done"), nil
+	})
+
+	port := rand.IntN(1000) + 10080
+	go server.Start(port)
+	defer server.Shutdown(context.Background())
+
+	dial := func() net.Conn { return dialServer(t, port) }
+
+	// La primera conexión ocupa el único worker
+	first := dial()
+	defer first.Close()
+	fmt.Fprint(first, "GET /slow HTTP/1.1\r\nConnection: close\r\n\r\n")
+	<-started
+
+	// La segunda espera en la cola
+	second := dial()
+	defer second.Close()
+	fmt.Fprint(second, "GET /slow HTTP/1.1\r\nConnection: close\r\n\r\n")
+	for i := 0; i < 50 && server.Stats().QueueDepth < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := server.Stats()
+	if stats.BusyWorkers != 1 || stats.QueueDepth != 1 || stats.QueueCapacity != 1 {
+		t.Errorf("Expected 1 busy worker and 1 queued connection, not %+v", stats)
+	}
+
+	// Act: la tercera no cabe
+	third := dial()
+	defer third.Close()
+	status, headers, _ := readTestResponse(t, bufio.NewReader(third))
+
+	// Assert
+	if status != "HTTP/1.0 503 Service Unavailable" {
+		t.Errorf("Expected 503, not %q", status)
+	}
+	if headers["Retry-After"] != "2" {
+		t.Errorf("Expected Retry-After 2, not %q", headers["Retry-After"])
+	}
+	if server.Stats().Rejected != 1 {
+		t.Errorf("Expected 1 rejected connection, not %d", server.Stats().Rejected)
+	}
+
+	// Las conexiones admitidas se atienden al liberar el worker
+	close(release)
+	for _, conn := range []net.Conn{first, second} {
+		status, _, body := readTestResponse(t, bufio.NewReader(conn))
+		if status != "HTTP/1.1 200 OK" || body != "done" {
+			t.Errorf("Expected 200 done, not %s %q", status, body)
+		}
+	}
+}
+
+func TestWorkerPoolRejectsWithoutBlockingAccept(t *testing.T) {
+	// Arrange: el único worker y la cola quedan ocupados
+	config := DefaultConfig()
+	config.HandleSignals = false
+	config.Workers = 1
+	config.QueueSize = 1
+	server := NewHttpServerWithConfig(config)
+
+	release := make(chan struct{})
+	server.Get("/slow", func(request *HttpRequest) (*HttpResponse, error) {
+		<-release
+		return Ok(), nil
+	})
+
+	port := rand.IntN(1000) + 12080
+	go server.Start(port)
+	defer server.Shutdown(context.Background())
+	defer close(release)
+
+	for i := 0; i < 2; i++ {
+		conn := dialServer(t, port)
+		defer conn.Close()
+		fmt.Fprint(conn, "GET /slow HTTP/1.1\r\nConnection: close\r\n\r\n")
+	}
+	for i := 0; i < 50 && server.Stats().QueueDepth < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Act: muchas conexiones rechazadas que no envían nada
+	const silent = 2 * rejectSlots
+	start := time.Now()
+	for i := 0; i < silent; i++ {
+		conn := dialServer(t, port)
+		defer conn.Close()
+	}
+	for i := 0; i < 100 && server.Stats().Rejected < silent; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Assert: cada una esperaría rejectTimeout si el bucle de aceptación las atendiera
+	if rejected := server.Stats().Rejected; rejected != silent {
+		t.Fatalf("Expected %d rejected connections, not %d", silent, rejected)
+	}
+	if elapsed := time.Since(start); elapsed > silent*rejectTimeout/4 {
+		t.Errorf("Expected the accept loop not to wait for silent clients, took %v", elapsed)
+	}
+}
+
+func TestWorkerPoolEvictsIdleConnsWhenQueued(t *testing.T) {
+	// Arrange: un worker y un plazo de inactividad largo
+	config := DefaultConfig()
+	config.HandleSignals = false
+	config.Workers = 1
+	config.QueueSize = 1
+	config.IdleTimeout = time.Minute
+	server := NewHttpServerWithConfig(config)
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("pong"), nil
+	})
+
+	port := rand.IntN(1000) + 11080
+	go server.Start(port)
+	defer server.Shutdown(context.Background())
+
+	// La primera conexión queda inactiva y retiene el único worker
+	idle := dialServer(t, port)
+	defer idle.Close()
+	idleReader := bufio.NewReader(idle)
+	fmt.Fprint(idle, "GET /ping HTTP/1.1\r\nHost: test\r\n\r\n")
+	if status, _, _ := readTestResponse(t, idleReader); status != "HTTP/1.1 200 OK" {
+		t.Fatalf("Expected 200, not %q", status)
+	}
+
+	// Act: llega otra conexión que tiene que esperar en la cola
+	queued := dialServer(t, port)
+	defer queued.Close()
+	queued.SetReadDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprint(queued, "GET /ping HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	// Assert: se atiende sin esperar IdleTimeout y la inactiva se cierra
+	status, _, body := readTestResponse(t, bufio.NewReader(queued))
+	if status != "HTTP/1.1 200 OK" || body != "pong" {
+		t.Errorf("Expected 200 pong, not %s %q", status, body)
+	}
+	idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := idleReader.ReadByte(); err != io.EOF {
+		t.Errorf("Expected the idle connection to be closed, not %v", err)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		wait     time.Duration
+		expected string
+	}{
+		{0, "1"},
+		{500 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	}
+
+	for _, test := range tests {
+		if got := retryAfterSeconds(test.wait); got != test.expected {
+			t.Errorf("Expected %v to be %q, not %q", test.wait, test.expected, got)
+		}
+	}
+}
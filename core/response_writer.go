@@ -0,0 +1,132 @@
+package core
+
+import (
+	"bufio"
+	"io"
+)
+
+// Define el tipo para las funciones que generan el cuerpo de la respuesta de forma incremental.
+// Recibe la solicitud y un ResponseWriter sobre el que escribir cabeceras y cuerpo.
+type StreamHandle func(request *HttpRequest, writer *ResponseWriter) error
+
+// Convierte un StreamHandle en un Handle normal.
+// El Handle devuelve una respuesta 200 OK cuyo cuerpo lo produce el StreamHandle
+// cuando el servidor escribe la respuesta en la conexión.
+func Stream(handle StreamHandle) Handle {
+	return func(request *HttpRequest) (*HttpResponse, error) {
+		response := Ok()
+		response.stream = func(writer *ResponseWriter) error {
+			return handle(request, writer)
+		}
+		return response, nil
+	}
+}
+
+// Permite a un manejador escribir una respuesta por partes.
+// Las cabeceras se envían con la primera escritura (o al llamar a Flush);
+// después ya no pueden modificarse.
+type ResponseWriter struct {
+	response    *HttpResponse
+	conn        io.Writer
+	buffer      *bufio.Writer
+	chunked     *chunkedWriter
+	wroteHeader bool
+}
+
+// Crea un ResponseWriter que escribe la respuesta sobre la conexión dada.
+func newResponseWriter(response *HttpResponse, conn io.Writer) *ResponseWriter {
+	return &ResponseWriter{
+		response: response,
+		conn:     conn,
+	}
+}
+
+// Establece una cabecera HTTP. No tiene efecto si las cabeceras ya se enviaron.
+func (writer *ResponseWriter) SetHeader(key, value string) *ResponseWriter {
+	if !writer.wroteHeader {
+		writer.response.SetHeader(key, value)
+	}
+	return writer
+}
+
+// Establece el código y el texto del estado y envía las cabeceras.
+// No tiene efecto si las cabeceras ya se enviaron.
+func (writer *ResponseWriter) WriteHeader(statusCode int, statusText string) error {
+	if writer.wroteHeader {
+		return nil
+	}
+
+	writer.response.SetStatusCode(statusCode)
+	writer.response.SetStatusText(statusText)
+
+	return writer.writeHeader()
+}
+
+// Indica si las cabeceras ya fueron enviadas al cliente.
+func (writer *ResponseWriter) HeaderWritten() bool {
+	return writer.wroteHeader
+}
+
+// Escribe una parte del cuerpo. Envía las cabeceras si aún no se enviaron.
+func (writer *ResponseWriter) Write(data []byte) (int, error) {
+	if err := writer.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	return writer.buffer.Write(data)
+}
+
+// Escribe una cadena como parte del cuerpo.
+func (writer *ResponseWriter) WriteString(data string) (int, error) {
+	return writer.Write([]byte(data))
+}
+
+// Envía al cliente todo lo escrito hasta ahora.
+func (writer *ResponseWriter) Flush() error {
+	if err := writer.writeHeader(); err != nil {
+		return err
+	}
+
+	return writer.buffer.Flush()
+}
+
+// Envía la línea de estado y las cabeceras y prepara el cuerpo.
+func (writer *ResponseWriter) writeHeader() error {
+	if writer.wroteHeader {
+		return nil
+	}
+	writer.wroteHeader = true
+
+	// La longitud es desconocida: no se envía Content-Length.
+	writer.response.Headers.Del("Content-Length")
+	if writer.response.Chunked() {
+		writer.response.SetHeader("Transfer-Encoding", "chunked")
+	}
+
+	if _, err := io.WriteString(writer.conn, writer.response.head()); err != nil {
+		return err
+	}
+
+	if writer.response.Chunked() {
+		writer.chunked = newChunkedWriter(writer.conn)
+		writer.buffer = bufio.NewWriter(writer.chunked)
+	} else {
+		// HTTP/1.0: el fin del cuerpo lo marca el cierre de la conexión.
+		writer.buffer = bufio.NewWriter(writer.conn)
+	}
+
+	return nil
+}
+
+// Termina la respuesta: vacía el búfer y, si corresponde, envía el bloque final.
+func (writer *ResponseWriter) close() error {
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if writer.chunked != nil {
+		return writer.chunked.Close()
+	}
+
+	return nil
+}
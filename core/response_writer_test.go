@@ -0,0 +1,140 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// Ejecuta un único intercambio solicitud/respuesta contra el servidor y devuelve la respuesta cruda.
+func roundTrip(t *testing.T, server *HttpServer, request string) string {
+	t.Helper()
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	go fmt.Fprint(client, request)
+
+	data, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("Expected no error, %v", err)
+	}
+
+	return string(data)
+}
+
+func TestStreamChunked(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		writer.SetHeader("Content-Type", "text/plain")
+		writer.WriteString("first")
+		writer.Flush()
+		writer.WriteString("second")
+		return nil
+	}))
+
+	// Act
+	response := roundTrip(t, server, "GET /stream HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	// Assert
+	expected := "HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"5\r\nfirst\r\n6\r\nsecond\r\n0\r\n\r\n"
+	if response != expected {
+		t.Errorf("Expected %q, not %q", expected, response)
+	}
+}
+
+func TestStreamHttp10(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		writer.WriteString("first")
+		writer.Flush()
+		writer.WriteString("second")
+		return nil
+	}))
+
+	// Act: aunque el cliente pida keep-alive, el cuerpo termina con el cierre de la conexión
+	response := roundTrip(t, server, "GET /stream HTTP/1.0\r\nConnection: keep-alive\r\n\r\n")
+
+	// Assert
+	expected := "HTTP/1.0 200 OK\r\nConnection: close\r\n\r\nfirstsecond"
+	if response != expected {
+		t.Errorf("Expected %q, not %q", expected, response)
+	}
+}
+
+func TestStreamWriteHeader(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		writer.WriteHeader(202, "Accepted")
+		// Después de enviar las cabeceras ya no se pueden modificar
+		writer.SetHeader("X-Late", "ignored")
+		writer.WriteHeader(500, "Internal Server Error")
+		return nil
+	}))
+
+	// Act
+	response := roundTrip(t, server, "GET /stream HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	// Assert
+	if !strings.HasPrefix(response, "HTTP/1.1 202 Accepted\r\n") {
+		t.Errorf("Expected 202 Accepted, not %q", response)
+	}
+
+	if strings.Contains(response, "X-Late") {
+		t.Errorf("Expected late header to be ignored, not %q", response)
+	}
+
+	if !strings.HasSuffix(response, "\r\n\r\n0\r\n\r\n") {
+		t.Errorf("Expected an empty chunked body, not %q", response)
+	}
+}
+
+func TestStreamErrorBeforeWrite(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		return fmt.Errorf("boom")
+	}))
+
+	// Act
+	response := roundTrip(t, server, "GET /stream HTTP/1.1\r\n\r\n")
+
+	// Assert
+	status, _, _ := strings.Cut(response, "\r\n")
+	if status != "HTTP/1.1 500 Internal Server Error" {
+		t.Errorf("Expected 500 status line, not %q", status)
+	}
+}
+
+func TestStreamErrorAfterWrite(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		writer.WriteString("partial")
+		writer.Flush()
+		return fmt.Errorf("boom")
+	}))
+
+	// Act
+	response := roundTrip(t, server, "GET /stream HTTP/1.1\r\n\r\n")
+
+	// Assert: la respuesta queda truncada, sin el bloque final
+	reader := bufio.NewReader(strings.NewReader(response))
+	status, _ := reader.ReadString('\n')
+	if status != "HTTP/1.1 200 OK\r\n" {
+		t.Errorf("Expected 200 status line, not %q", status)
+	}
+
+	if !strings.HasSuffix(response, "7\r\npartial\r\n") {
+		t.Errorf("Expected truncated chunked body, not %q", response)
+	}
+}
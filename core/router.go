@@ -0,0 +1,84 @@
+package core
+
+import "strings"
+
+// Resuelve qué manejador atiende cada solicitud.
+// HttpServer delega en un Router el registro de rutas y el despacho, de modo que
+// la semántica de coincidencia, precedencia y errores (404, método incorrecto)
+// vive en un solo lugar y puede reemplazarse por otra implementación.
+type Router interface {
+	// Registra un manejador para el método y el patrón de ruta dados.
+	AddRoute(method, path string, handle Handle)
+	// Devuelve los manejadores registrados.
+	Routes() []Handler
+	// Despacha la solicitud al manejador correspondiente y devuelve su respuesta.
+	Route(request *HttpRequest) (*HttpResponse, error)
+}
+
+// Implementación predeterminada de Router basada en una tabla de rutas.
+// Usa FindHandler, por lo que el resultado no depende del orden de registro.
+type RouteTable struct {
+	handlers []Handler
+}
+
+// Crea una tabla de rutas vacía.
+func NewRouteTable() *RouteTable {
+	return &RouteTable{handlers: []Handler{}}
+}
+
+// Agrega un nuevo manejador a la tabla.
+func (table *RouteTable) AddRoute(method, path string, handle Handle) {
+	handler := Handler{
+		Method: method,
+		Path:   path,
+		Handle: handle,
+	}
+
+	table.handlers = append(table.handlers, handler)
+}
+
+// Devuelve los manejadores registrados.
+func (table *RouteTable) Routes() []Handler {
+	return table.handlers
+}
+
+// Ordena los manejadores por la especificidad de la ruta (ver MoreSpecific).
+// El despacho no depende de este orden; solo sirve para listarlos de forma determinista.
+func (table *RouteTable) SortHandlers() {
+	SortHandlers(table.handlers)
+}
+
+// Despacha la solicitud al manejador más específico para su método y ruta.
+// Las solicitudes HEAD sin manejador propio usan el manejador GET (el servidor omite el cuerpo)
+// y las OPTIONS sin manejador propio se responden con 204 y la cabecera Allow.
+// Responde 405 con la cabecera Allow si la ruta existe pero no para ese método,
+// y 404 si la ruta no existe. Los valores capturados por la ruta quedan en request.Params.
+func (table *RouteTable) Route(request *HttpRequest) (*HttpResponse, error) {
+	path := request.Target.Path
+
+	handler, params, pathMatched := FindHandler(table.handlers, request.Method, path)
+	if handler == nil && request.Method == "HEAD" {
+		handler, params, _ = FindHandler(table.handlers, "GET", path)
+	}
+
+	if handler != nil {
+		// Método y ruta coinciden → ejecutar handler
+		request.Params = params
+		return handler.Handle(request)
+	}
+
+	allowed := AllowedMethods(table.handlers, path)
+
+	if request.Method == "OPTIONS" && (pathMatched || (path == "*" && allowed != nil)) {
+		// OPTIONS automático → lista de métodos permitidos
+		return NoContent().SetHeader("Allow", strings.Join(allowed, ", ")), nil
+	}
+
+	if pathMatched {
+		// Ruta conocida + método incorrecto → 405 Method Not Allowed
+		return MethodNotAllowed(allowed).Text("405 Method Not Allowed"), nil
+	}
+
+	// Ruta desconocida → 404 Not Found
+	return NotFound().Text("404 Not Found"), nil
+}
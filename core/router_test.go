@@ -0,0 +1,175 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Router de prueba que responde siempre lo mismo y registra las rutas agregadas.
+type stubRouter struct {
+	handlers []Handler
+}
+
+func (router *stubRouter) AddRoute(method, path string, handle Handle) {
+	router.handlers = append(router.handlers, Handler{Method: method, Path: path, Handle: handle})
+}
+
+func (router *stubRouter) Routes() []Handler {
+	return router.handlers
+}
+
+func (router *stubRouter) Route(request *HttpRequest) (*HttpResponse, error) {
+	return Ok().Text("stub " + request.Target.Path), nil
+}
+
+func TestRouteTableRoute(t *testing.T) {
+	// Arrange
+	table := NewRouteTable()
+	table.AddRoute("GET", "/files/{name}", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text(request.Param("name")), nil
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/files/a.txt", 200, "a.txt"},
+		{"POST", "/files/a.txt", 405, "405 Method Not Allowed"},
+		{"GET", "/other", 404, "404 Not Found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			target, _ := url.Parse(test.path)
+
+			// Act
+			response, err := table.Route(NewHttpRequest(test.method, target, map[string]string{}, ""))
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, %v", err)
+			}
+
+			if response.StatusCode != test.status || response.Body != test.body {
+				t.Errorf("Expected %d %q, not %d %q", test.status, test.body, response.StatusCode, response.Body)
+			}
+		})
+	}
+}
+
+func TestServerCustomRouter(t *testing.T) {
+	// Arrange
+	router := &stubRouter{}
+	server := NewHttpServer()
+	server.Router = router
+
+	server.Get("/ignored", nil)
+
+	// Act
+	response := roundTrip(t, server, "GET /anything HTTP/1.0\r\n\r\n")
+
+	// Assert
+	if len(router.Routes()) != 1 {
+		t.Errorf("Expected route to be registered in the custom router")
+	}
+
+	if !strings.HasSuffix(response, "\r\n\r\nstub /anything") {
+		t.Errorf("Expected response from the custom router, not %q", response)
+	}
+}
+
+func TestRouteTableMethodNotAllowed(t *testing.T) {
+	// Arrange
+	handle := func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	}
+
+	table := NewRouteTable()
+	table.AddRoute("GET", "/files/{name}", handle)
+	table.AddRoute("DELETE", "/files/{name}", handle)
+	table.AddRoute("POST", "/upload", handle)
+
+	target, _ := url.Parse("/files/a.txt")
+
+	// Act
+	response, _ := table.Route(NewHttpRequest("PUT", target, map[string]string{}, ""))
+
+	// Assert
+	if response.StatusCode != 405 {
+		t.Errorf("Expected status code to be 405, not %d", response.StatusCode)
+	}
+
+	expected := "DELETE, GET, HEAD, OPTIONS"
+	if response.Headers.Get("Allow") != expected {
+		t.Errorf("Expected Allow to be %q, not %q", expected, response.Headers.Get("Allow"))
+	}
+}
+
+func TestRouteTableOptions(t *testing.T) {
+	// Arrange
+	handle := func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	}
+
+	table := NewRouteTable()
+	table.AddRoute("GET", "/files/{name}", handle)
+	table.AddRoute("POST", "/upload", handle)
+
+	tests := []struct {
+		path   string
+		status int
+		allow  string
+	}{
+		{"/files/a.txt", 204, "GET, HEAD, OPTIONS"},
+		{"*", 204, "GET, HEAD, OPTIONS, POST"},
+		{"/missing", 404, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			target, _ := url.Parse(test.path)
+
+			// Act
+			response, _ := table.Route(NewHttpRequest("OPTIONS", target, map[string]string{}, ""))
+
+			// Assert
+			if response.StatusCode != test.status {
+				t.Errorf("Expected status code to be %d, not %d", test.status, response.StatusCode)
+			}
+
+			if response.Headers.Get("Allow") != test.allow {
+				t.Errorf("Expected Allow to be %q, not %q", test.allow, response.Headers.Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestServerHead(t *testing.T) {
+	// Arrange
+	server := NewHttpServer()
+	server.Get("/text", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("Content"), nil
+	})
+	server.Get("/stream", Stream(func(request *HttpRequest, writer *ResponseWriter) error {
+		t.Errorf("Expected stream not to run for HEAD")
+		return nil
+	}))
+
+	// Act
+	text := roundTrip(t, server, "HEAD /text HTTP/1.0\r\n\r\n")
+	stream := roundTrip(t, server, "HEAD /stream HTTP/1.1\r\nConnection: close\r\n\r\n")
+
+	// Assert: mismas cabeceras que GET (incluido Content-Length) y sin cuerpo
+	expected := "HTTP/1.0 200 OK\r\nConnection: close\r\nContent-Length: 7\r\nContent-Type: text/plain\r\n\r\n"
+	if text != expected {
+		t.Errorf("Expected %q, not %q", expected, text)
+	}
+
+	expected = "HTTP/1.1 200 OK\r\nConnection: close\r\nTransfer-Encoding: chunked\r\n\r\n"
+	if stream != expected {
+		t.Errorf("Expected %q, not %q", expected, stream)
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Clase de prioridad de una ruta.
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Trabajos costosos en CPU o tiempo (ej. /sleep, /matrix/part)
+	PriorityNormal                 // Solicitudes normales
+	PriorityHigh                   // Rutas de control (ej. /ping, /status): nunca esperan
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// Error devuelto cuando el planificador no puede admitir más solicitudes.
+var ErrSchedulerBusy = errors.New("scheduler busy")
+
+// Reparte un número fijo de turnos de ejecución entre las rutas según su prioridad.
+//
+// Una solicitud de prioridad alta se ejecuta siempre, sin ocupar turno.
+// Las demás ocupan un turno; si no hay ninguno libre esperan en una cola
+// acotada, y al liberarse un turno se entrega a la solicitud en espera de
+// mayor prioridad (y, entre iguales, a la más antigua). Si la cola está
+// llena o la espera supera el plazo, la solicitud se rechaza con 503.
+//
+// Como la espera ocupa un worker del servidor, conviene que slots + queueSize
+// sea menor que Config.Workers: así siempre quedan workers para las rutas de
+// prioridad alta.
+type Scheduler struct {
+	mu      sync.Mutex
+	slots   int                            // Turnos de ejecución
+	running int                            // Turnos ocupados
+	queue   int                            // Máximo de solicitudes en espera
+	waiting [numPriorities][]chan struct{} // Solicitudes en espera por prioridad
+	timeout time.Duration                  // Espera máxima por un turno (0 = sin límite)
+}
+
+// Estado del planificador en un momento dado.
+type SchedulerStats struct {
+	Slots   int `json:"slots"`   // Turnos de ejecución
+	Running int `json:"running"` // Turnos ocupados
+	Waiting int `json:"waiting"` // Solicitudes esperando un turno
+}
+
+// Crea un planificador con el número de turnos, el tamaño de la cola de espera
+// y la espera máxima por un turno dados.
+func NewScheduler(slots, queueSize int, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		slots:   slots,
+		queue:   queueSize,
+		timeout: timeout,
+	}
+}
+
+// Middleware que ejecuta la ruta con la prioridad dada.
+// Uso: server.Get("/sleep", advanced.SleepHandler, scheduler.Priority(core.PriorityLow))
+func (scheduler *Scheduler) Priority(priority Priority) Middleware {
+	return func(next Handle) Handle {
+		return func(request *HttpRequest) (*HttpResponse, error) {
+			if priority >= PriorityHigh {
+				return next(request)
+			}
+
+			if err := scheduler.acquire(request.Context(), priority); err != nil {
+				if !errors.Is(err, ErrSchedulerBusy) {
+					// La solicitud se canceló o venció su plazo mientras esperaba
+					return nil, err
+				}
+				return ServiceUnavailable(scheduler.timeout).Text(err.Error()), nil
+			}
+			defer scheduler.release()
+
+			return next(request)
+		}
+	}
+}
+
+// Devuelve el estado actual del planificador.
+func (scheduler *Scheduler) Stats() SchedulerStats {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	return SchedulerStats{
+		Slots:   scheduler.slots,
+		Running: scheduler.running,
+		Waiting: scheduler.waitingLocked(),
+	}
+}
+
+// Ocupa un turno, esperando si hace falta mientras ctx siga vigente.
+func (scheduler *Scheduler) acquire(ctx context.Context, priority Priority) error {
+	scheduler.mu.Lock()
+
+	// Si hay un turno libre no hay nadie esperando: release entrega los turnos a la cola
+	if scheduler.running < scheduler.slots {
+		scheduler.running++
+		scheduler.mu.Unlock()
+		return nil
+	}
+
+	if scheduler.waitingLocked() >= scheduler.queue {
+		scheduler.mu.Unlock()
+		return ErrSchedulerBusy
+	}
+
+	ready := make(chan struct{})
+	scheduler.waiting[priority] = append(scheduler.waiting[priority], ready)
+	scheduler.mu.Unlock()
+
+	var expired <-chan time.Time
+	if scheduler.timeout > 0 {
+		timer := time.NewTimer(scheduler.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	err := ErrSchedulerBusy
+	select {
+	case <-ready:
+		return nil
+	case <-expired:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	scheduler.mu.Lock()
+	removed := scheduler.removeLocked(priority, ready)
+	scheduler.mu.Unlock()
+
+	if !removed {
+		// El turno llegó mientras expiraba el plazo: se aprovecha, salvo que la
+		// solicitud ya no lo necesite, y entonces pasa a la siguiente
+		if ctx.Err() == nil {
+			return nil
+		}
+		scheduler.release()
+	}
+
+	return err
+}
+
+// Libera un turno, entregándolo a la solicitud en espera de mayor prioridad.
+func (scheduler *Scheduler) release() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	for priority := numPriorities - 1; priority >= 0; priority-- {
+		if waiting := scheduler.waiting[priority]; len(waiting) > 0 {
+			scheduler.waiting[priority] = waiting[1:]
+			// El turno pasa directamente a la solicitud en espera
+			close(waiting[0])
+			return
+		}
+	}
+
+	scheduler.running--
+}
+
+// Cuenta las solicitudes en espera. Requiere tener scheduler.mu.
+func (scheduler *Scheduler) waitingLocked() int {
+	count := 0
+	for _, waiting := range scheduler.waiting {
+		count += len(waiting)
+	}
+	return count
+}
+
+// Quita una solicitud de la cola y devuelve si seguía en ella. Requiere tener scheduler.mu.
+func (scheduler *Scheduler) removeLocked(priority Priority, ready chan struct{}) bool {
+	waiting := scheduler.waiting[priority]
+	for i, candidate := range waiting {
+		if candidate == ready {
+			scheduler.waiting[priority] = append(waiting[:i:i], waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware que limita a max las ejecuciones simultáneas de una ruta.
+// Las solicitudes que superan el límite se rechazan de inmediato con 503.
+// El límite cubre la ejecución del manejador, no la escritura de la respuesta.
+// Cada llamada crea un límite propio; las rutas que reciben el mismo Middleware lo comparten.
+// Uso: server.Get("/sleep", advanced.SleepHandler, core.ConcurrencyLimit(4))
+func ConcurrencyLimit(max int) Middleware {
+	slots := make(chan struct{}, max)
+
+	return func(next Handle) Handle {
+		return func(request *HttpRequest) (*HttpResponse, error) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				return ServiceUnavailable(time.Second).Text("too many concurrent requests"), nil
+			}
+			defer func() { <-slots }()
+
+			return next(request)
+		}
+	}
+}
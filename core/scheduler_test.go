@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Devuelve un Handle que avisa al empezar y espera a que se cierre release.
+func blockingHandle(started chan<- string, release <-chan struct{}, name string) Handle {
+	return func(request *HttpRequest) (*HttpResponse, error) {
+		started <- name
+		<-release
+		return Ok().Text(name), nil
+	}
+}
+
+func schedulerRequest() *HttpRequest {
+	target, _ := url.Parse("/")
+	return NewHttpRequest("GET", target, map[string]string{}, "")
+}
+
+func TestSchedulerHighPriorityBypassesSlots(t *testing.T) {
+	// Arrange: el único turno está ocupado
+	scheduler := NewScheduler(1, 1, time.Second)
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	defer close(release)
+
+	low := scheduler.Priority(PriorityLow)(blockingHandle(started, release, "low"))
+	go low(schedulerRequest())
+	<-started
+
+	high := scheduler.Priority(PriorityHigh)(func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok().Text("pong"), nil
+	})
+
+	// Act
+	response, _ := high(schedulerRequest())
+
+	// Assert
+	if response.StatusCode != 200 {
+		t.Errorf("Expected high priority to run, not %d", response.StatusCode)
+	}
+}
+
+func TestSchedulerServesHigherPriorityFirst(t *testing.T) {
+	// Arrange
+	scheduler := NewScheduler(1, 2, time.Second)
+	started := make(chan string, 3)
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	run := func(priority Priority, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scheduler.Priority(priority)(blockingHandle(started, release, name))(schedulerRequest())
+		}()
+	}
+
+	run(PriorityLow, "first")
+	<-started
+
+	// Una solicitud baja llega antes que una normal
+	run(PriorityLow, "low")
+	for scheduler.Stats().Waiting < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	run(PriorityNormal, "normal")
+	for scheduler.Stats().Waiting < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Act
+	close(release)
+	order := []string{<-started, <-started}
+	wg.Wait()
+
+	// Assert
+	if order[0] != "normal" || order[1] != "low" {
+		t.Errorf("Expected normal before low, not %v", order)
+	}
+	if stats := scheduler.Stats(); stats.Running != 0 || stats.Waiting != 0 {
+		t.Errorf("Expected scheduler to be idle, not %+v", stats)
+	}
+}
+
+func TestSchedulerRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   int
+		timeout time.Duration
+	}{
+		{"queue full", 0, time.Second},
+		{"timeout", 1, 20 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			scheduler := NewScheduler(1, test.queue, test.timeout)
+			started := make(chan string, 1)
+			release := make(chan struct{})
+			defer close(release)
+
+			go scheduler.Priority(PriorityLow)(blockingHandle(started, release, "busy"))(schedulerRequest())
+			<-started
+
+			// Act
+			response, _ := scheduler.Priority(PriorityNormal)(func(request *HttpRequest) (*HttpResponse, error) {
+				return Ok(), nil
+			})(schedulerRequest())
+
+			// Assert
+			if response.StatusCode != 503 || response.Headers.Get("Retry-After") == "" {
+				t.Errorf("Expected 503 with Retry-After, not %d", response.StatusCode)
+			}
+			if stats := scheduler.Stats(); stats.Waiting != 0 {
+				t.Errorf("Expected no waiting requests, not %d", stats.Waiting)
+			}
+		})
+	}
+}
+
+func TestSchedulerStopsWaitingWhenCancelled(t *testing.T) {
+	// Arrange: el único turno está ocupado
+	scheduler := NewScheduler(1, 1, time.Minute)
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	go scheduler.Priority(PriorityLow)(blockingHandle(started, release, "busy"))(schedulerRequest())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	response, err := scheduler.Priority(PriorityNormal)(func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	})(schedulerRequest().WithContext(ctx))
+
+	// Assert: se abandona la espera con el error del contexto, no con 503
+	if !errors.Is(err, context.DeadlineExceeded) || response != nil {
+		t.Errorf("Expected context.DeadlineExceeded, not %v", err)
+	}
+	if stats := scheduler.Stats(); stats.Waiting != 0 || stats.Running != 1 {
+		t.Errorf("Expected 1 running and no waiting requests, not %+v", stats)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	// Arrange
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	handle := ConcurrencyLimit(1)(blockingHandle(started, release, "slow"))
+
+	done := make(chan *HttpResponse)
+	go func() {
+		response, _ := handle(schedulerRequest())
+		done <- response
+	}()
+	<-started
+
+	// Act
+	rejected, _ := handle(schedulerRequest())
+	close(release)
+	accepted := <-done
+
+	// Assert
+	if rejected.StatusCode != 503 {
+		t.Errorf("Expected 503 over the limit, not %d", rejected.StatusCode)
+	}
+	if accepted.StatusCode != 200 {
+		t.Errorf("Expected 200 within the limit, not %d", accepted.StatusCode)
+	}
+
+	// Al terminar, el límite vuelve a admitir solicitudes
+	go func() { <-started }()
+	if response, _ := handle(schedulerRequest()); response.StatusCode != 200 {
+		t.Errorf("Expected 200 after release, not %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Intervalo con el que Shutdown revisa si quedan conexiones activas.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Cierra el servidor de forma ordenada.
+// Deja de aceptar conexiones, cierra las conexiones inactivas y espera a que las
+// activas terminen de responder su solicitud en curso. Si el contexto termina
+// antes, cierra a la fuerza las conexiones restantes y devuelve el error del contexto.
+func (server *HttpServer) Shutdown(ctx context.Context) error {
+	server.inShutdown.Store(true)
+
+	// Deja de aceptar conexiones nuevas.
+	server.Stop()
+
+	defer server.closeDone()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if server.closeIdleConns() {
+			slog.Info("Server drained")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			server.closeAllConns()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Devuelve un contexto que expira tras Config.ShutdownTimeout (o nunca, si es cero).
+func (server *HttpServer) shutdownContext() (context.Context, context.CancelFunc) {
+	if server.Config.ShutdownTimeout > 0 {
+		return context.WithTimeout(context.Background(), server.Config.ShutdownTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Estado de una conexión abierta.
+type connState int
+
+const (
+	connNew    connState = iota // Aceptada, esperando su primera solicitud
+	connActive                  // Atendiendo una solicitud
+	connIdle                    // Persistente, esperando la siguiente solicitud
+)
+
+// Registra una conexión abierta y su estado.
+func (server *HttpServer) trackConn(conn net.Conn, state connState) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if server.conns == nil {
+		server.conns = make(map[net.Conn]connState)
+	}
+	server.conns[conn] = state
+}
+
+// Elimina una conexión cerrada del registro.
+func (server *HttpServer) untrackConn(conn net.Conn) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	delete(server.conns, conn)
+}
+
+// Cierra las conexiones inactivas y devuelve true si no queda ninguna abierta.
+func (server *HttpServer) closeIdleConns() bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for conn, state := range server.conns {
+		if state != connActive {
+			conn.Close()
+			delete(server.conns, conn)
+		}
+	}
+
+	return len(server.conns) == 0
+}
+
+// Interrumpe la espera de las conexiones persistentes inactivas para que su
+// worker quede libre. Handle las cierra sin responder, como al vencer IdleTimeout.
+// Una conexión cuya siguiente solicitud ya llegó pasa a activa (con su propio
+// plazo de lectura) antes de leerla, así que no se ve afectada.
+func (server *HttpServer) evictIdleConns() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for conn, state := range server.conns {
+		if state == connIdle {
+			conn.SetReadDeadline(aLongTimeAgo)
+		}
+	}
+}
+
+// Cierra todas las conexiones, incluidas las que atienden una solicitud.
+func (server *HttpServer) closeAllConns() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for conn := range server.conns {
+		conn.Close()
+		delete(server.conns, conn)
+	}
+}
+
+// Devuelve un canal que se cierra cuando termina el Shutdown.
+func (server *HttpServer) drained() <-chan struct{} {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return server.doneChan()
+}
+
+// Cierra el canal devuelto por drained.
+func (server *HttpServer) closeDone() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	done := server.doneChan()
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+}
+
+// Devuelve el canal done, creándolo si hace falta. Requiere tener server.mu.
+func (server *HttpServer) doneChan() chan struct{} {
+	if server.done == nil {
+		server.done = make(chan struct{})
+	}
+	return server.done
+}
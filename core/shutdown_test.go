@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForActiveRequest(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.HandleSignals = false
+	server := NewHttpServerWithConfig(config)
+
+	started := make(chan struct{})
+	server.Get("/slow", func(request *HttpRequest) (*HttpResponse, error) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		return Ok().Text("done"), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	go fmt.Fprint(client, "GET /slow HTTP/1.1\r\n\r\n")
+	<-started
+
+	// Act
+	result := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		result <- server.Shutdown(ctx)
+	}()
+
+	status, headers, body := readTestResponse(t, bufio.NewReader(client))
+
+	// Assert: la solicitud en curso termina y la conexión se cierra después
+	if status != "HTTP/1.1 200 OK" || body != "done" {
+		t.Errorf("Expected in-flight request to complete, not %s %q", status, body)
+	}
+
+	if headers["Connection"] != "close" {
+		t.Errorf("Expected Connection close during shutdown, not %s", headers["Connection"])
+	}
+
+	if err := <-result; err != nil {
+		t.Errorf("Expected no error, %v", err)
+	}
+}
+
+func TestShutdownClosesIdleConnections(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.HandleSignals = false
+	config.IdleTimeout = time.Minute
+	server := NewHttpServerWithConfig(config)
+	server.Get("/ping", func(request *HttpRequest) (*HttpResponse, error) {
+		return Ok(), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	reader := bufio.NewReader(client)
+	fmt.Fprint(client, "GET /ping HTTP/1.1\r\n\r\n")
+	readTestResponse(t, reader)
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := server.Shutdown(ctx)
+
+	// Assert: la conexión inactiva no retrasa el cierre
+	if err != nil {
+		t.Errorf("Expected no error, %v", err)
+	}
+
+	if _, err := reader.ReadByte(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected idle connection to be closed, not %v", err)
+	}
+}
+
+func TestShutdownForcesCloseAfterDeadline(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.HandleSignals = false
+	server := NewHttpServerWithConfig(config)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server.Get("/stuck", func(request *HttpRequest) (*HttpResponse, error) {
+		close(started)
+		<-release
+		return Ok(), nil
+	})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	go server.Handle(conn)
+
+	go fmt.Fprint(client, "GET /stuck HTTP/1.1\r\n\r\n")
+	<-started
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+
+	// Assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, not %v", err)
+	}
+
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Expected connection to be force-closed")
+	}
+}
+
+func TestStartReturnsAfterShutdown(t *testing.T) {
+	// Arrange
+	config := DefaultConfig()
+	config.HandleSignals = false
+	server := NewHttpServerWithConfig(config)
+
+	port := rand.IntN(1000) + 9080
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start(port)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Act
+	err := server.Shutdown(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected no error, %v", err)
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected Start to return nil, not %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected Start to return after Shutdown")
+	}
+}
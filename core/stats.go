@@ -0,0 +1,44 @@
+package core
+
+import "sync/atomic"
+
+// Métricas del servidor en un momento dado.
+type ServerStats struct {
+	Panics   int64 `json:"panics"`    // Pánicos recuperados desde el arranque
+	InFlight int64 `json:"in_flight"` // Solicitudes en manejo ahora mismo
+
+	Workers       int   `json:"workers"`        // Tamaño del grupo de workers (0 = sin grupo)
+	BusyWorkers   int64 `json:"busy_workers"`   // Workers atendiendo una conexión
+	QueueDepth    int   `json:"queue_depth"`    // Conexiones en cola esperando un worker
+	QueueCapacity int   `json:"queue_capacity"` // Tamaño máximo de la cola
+	Rejected      int64 `json:"rejected"`       // Conexiones rechazadas con 503 por cola llena
+}
+
+// Contadores internos del servidor, actualizados de forma atómica.
+type serverCounters struct {
+	panics   atomic.Int64
+	rejected atomic.Int64
+	inFlight atomic.Int64
+}
+
+// Devuelve las métricas actuales del servidor.
+func (server *HttpServer) Stats() ServerStats {
+	stats := ServerStats{
+		Panics:   server.counters.panics.Load(),
+		Rejected: server.counters.rejected.Load(),
+		InFlight: server.counters.inFlight.Load(),
+	}
+
+	server.mu.Lock()
+	pool := server.pool
+	server.mu.Unlock()
+
+	if pool != nil {
+		stats.Workers = pool.size
+		stats.BusyWorkers = pool.busy.Load()
+		stats.QueueDepth = len(pool.queue)
+		stats.QueueCapacity = cap(pool.queue)
+	}
+
+	return stats
+}
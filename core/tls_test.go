@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Crea un servidor con una ruta que informa si la solicitud llegó por TLS
+// y cuántos certificados presentó el cliente.
+func newTLSTestServer() *HttpServer {
+	config := DefaultConfig()
+	config.HandleSignals = false
+	server := NewHttpServerWithConfig(config)
+
+	server.Get("/tls", func(request *HttpRequest) (*HttpResponse, error) {
+		if request.TLS == nil {
+			return Ok().Text("plain"), nil
+		}
+		return Ok().Text(fmt.Sprintf("tls %d", len(request.TLS.PeerCertificates))), nil
+	})
+
+	return server
+}
+
+// Envía GET /tls por la conexión y devuelve el cuerpo de la respuesta.
+func getTLSPath(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	fmt.Fprint(conn, "GET /tls HTTP/1.1\r\nConnection: close\r\n\r\n")
+	_, _, body := readTestResponse(t, bufio.NewReader(conn))
+
+	return body
+}
+
+func TestServeTLS(t *testing.T) {
+	// Arrange
+	cert, err := SelfSignedCertificate("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	server := newTLSTestServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	go server.Serve(tls.NewListener(ln, NewTLSConfig(cert, nil)))
+	defer server.Shutdown(context.Background())
+
+	// Act
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer conn.Close()
+
+	// Assert
+	if body := getTLSPath(t, conn); body != "tls 0" {
+		t.Errorf("Expected tls 0, not %q", body)
+	}
+}
+
+func TestServeTLSClientCertificate(t *testing.T) {
+	// Arrange: el mismo certificado autofirmado actúa como CA de clientes
+	cert, err := SelfSignedCertificate("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	server := newTLSTestServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	go server.Serve(tls.NewListener(ln, NewTLSConfig(cert, roots)))
+	defer server.Shutdown(context.Background())
+
+	// Act & Assert: sin certificado de cliente la conexión se rechaza
+	anonymous, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots})
+	if err == nil {
+		anonymous.SetDeadline(time.Now().Add(time.Second))
+		fmt.Fprint(anonymous, "GET /tls HTTP/1.1\r\n\r\n")
+		if _, err = anonymous.Read(make([]byte, 1)); err == nil {
+			t.Errorf("Expected connection without client certificate to fail")
+		}
+		anonymous.Close()
+	}
+
+	// Con certificado de cliente la solicitud se atiende
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer conn.Close()
+
+	if body := getTLSPath(t, conn); body != "tls 1" {
+		t.Errorf("Expected tls 1, not %q", body)
+	}
+}
+
+func TestListenAndServeUnix(t *testing.T) {
+	// Arrange
+	server := newTLSTestServer()
+	path := filepath.Join(t.TempDir(), "server.sock")
+
+	go server.ListenAndServe("unix", path)
+	defer server.Shutdown(context.Background())
+
+	// Act
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("unix", path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer conn.Close()
+
+	// Assert
+	if body := getTLSPath(t, conn); body != "plain" {
+		t.Errorf("Expected plain, not %q", body)
+	}
+}
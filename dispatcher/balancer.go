@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Estrategia de balanceo: elige un worker entre los candidatos (activos y aún
+// no intentados para esta petición). key identifica la petición para las
+// estrategias que reparten por clave. Las implementaciones son seguras para
+// uso concurrente.
+type Strategy interface {
+	Name() string
+	Pick(candidates []*WorkerInfo, key string) *WorkerInfo
+}
+
+// Estrategias disponibles por nombre.
+var strategies = map[string]func() Strategy{
+	"round-robin":          func() Strategy { return &roundRobin{} },
+	"weighted-round-robin": func() Strategy { return &weightedRoundRobin{current: map[string]int{}} },
+	"least-outstanding":    func() Strategy { return leastOutstanding{} },
+	"power-of-two":         func() Strategy { return powerOfTwo{} },
+	"consistent-hash":      func() Strategy { return &consistentHash{replicas: 100} },
+	"least-loaded":         func() Strategy { return &leastLoaded{} },
+}
+
+// Crea la estrategia con el nombre dado.
+func NewStrategy(name string) (Strategy, error) {
+	create, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy: %q", name)
+	}
+	return create(), nil
+}
+
+// Devuelve los nombres de las estrategias, ordenados.
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Estrategia activa; se cambia con SetStrategy.
+var (
+	strategyMu sync.RWMutex
+	strategy   Strategy = &roundRobin{}
+)
+
+// Cambia la estrategia activa.
+func SetStrategy(s Strategy) {
+	strategyMu.Lock()
+	defer strategyMu.Unlock()
+	strategy = s
+}
+
+// Devuelve la estrategia activa.
+func CurrentStrategy() Strategy {
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+	return strategy
+}
+
+// Elige un worker activo que atienda method en path, con el circuit breaker
+// dispuesto a recibir peticiones y que no esté en exclude, según la estrategia activa. Un method vacío admite cualquier worker.
+// Devuelve nil si no queda ninguno.
+func PickWorker(method, path, key string, exclude map[string]bool) *WorkerInfo {
+	candidates := make([]*WorkerInfo, 0)
+	for _, wk := range ServingWorkers(method, path) {
+		if !exclude[wk.URL] {
+			candidates = append(candidates, wk)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return CurrentStrategy().Pick(candidates, key)
+}
+
+// URLs de todos los workers registrados, en cualquier estado, seguidas de los
+// candidatos que no estén registrados.
+func memberURLs(candidates []*WorkerInfo) []string {
+	mu.Lock()
+	urls := make([]string, 0, len(workers)+len(candidates))
+	known := make(map[string]bool, len(workers))
+	for _, wk := range workers {
+		urls = append(urls, wk.URL)
+		known[wk.URL] = true
+	}
+	mu.Unlock()
+
+	for _, wk := range candidates {
+		if !known[wk.URL] {
+			urls = append(urls, wk.URL)
+		}
+	}
+	return urls
+}
+
+// Clave de una petición para el hash consistente: la cabecera X-Request-Key
+// si existe o, si no, la ruta sin la consulta.
+func requestKey(url string, headers http.Header) string {
+	if key := headers.Get("X-Request-Key"); key != "" {
+		return key
+	}
+	path, _, _ := strings.Cut(url, "?")
+	return path
+}
+
+// StrategyHandler consulta (GET) o cambia (POST {"strategy": ...}) la estrategia activa.
+func StrategyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		var payload struct{ Strategy string }
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Bad JSON", http.StatusBadRequest)
+			return
+		}
+		s, err := NewStrategy(payload.Strategy)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		SetStrategy(s)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"strategy":  CurrentStrategy().Name(),
+		"available": StrategyNames(),
+	})
+}
+
+// --- Round-robin ---
+
+// Recorre los candidatos en orden circular.
+type roundRobin struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (s *roundRobin) Name() string { return "round-robin" }
+
+func (s *roundRobin) Pick(candidates []*WorkerInfo, _ string) *WorkerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wk := candidates[s.next%len(candidates)]
+	s.next = (s.next + 1) % len(candidates)
+	return wk
+}
+
+// --- Round-robin ponderado ---
+
+// Round-robin ponderado "suave" (como nginx): cada worker acumula su peso en
+// cada elección y el de mayor acumulado gana y resta el total. Reparte en
+// proporción al peso sin enviar rachas seguidas al mismo worker. Los acumulados
+// de los workers que dejan de estar registrados se descartan.
+type weightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func (s *weightedRoundRobin) Name() string { return "weighted-round-robin" }
+
+func (s *weightedRoundRobin) Pick(candidates []*WorkerInfo, _ string) *WorkerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best *WorkerInfo
+	total := 0
+	for _, wk := range candidates {
+		weight := wk.weight()
+		total += weight
+		s.current[wk.URL] += weight
+		if best == nil || s.current[wk.URL] > s.current[best.URL] {
+			best = wk
+		}
+	}
+	s.current[best.URL] -= total
+
+	if len(s.current) > len(candidates) {
+		s.prune(candidates)
+	}
+
+	return best
+}
+
+// Descarta los acumulados de los workers que ya no están registrados. Requiere
+// tener s.mu.
+func (s *weightedRoundRobin) prune(candidates []*WorkerInfo) {
+	members := make(map[string]bool, len(s.current))
+	for _, url := range memberURLs(candidates) {
+		members[url] = true
+	}
+	for url := range s.current {
+		if !members[url] {
+			delete(s.current, url)
+		}
+	}
+}
+
+// --- Menos peticiones en curso ---
+
+// Elige el worker con menos peticiones en curso; a igualdad, el primero.
+type leastOutstanding struct{}
+
+func (leastOutstanding) Name() string { return "least-outstanding" }
+
+func (leastOutstanding) Pick(candidates []*WorkerInfo, _ string) *WorkerInfo {
+	best := candidates[0]
+	for _, wk := range candidates[1:] {
+		if wk.outstanding.Load() < best.outstanding.Load() {
+			best = wk
+		}
+	}
+	return best
+}
+
+// --- Dos opciones al azar ---
+
+// Elige dos workers al azar y se queda con el de menos peticiones en curso.
+// Se acerca a least-outstanding sin que todos los dispatchers elijan el mismo worker.
+type powerOfTwo struct{}
+
+func (powerOfTwo) Name() string { return "power-of-two" }
+
+func (powerOfTwo) Pick(candidates []*WorkerInfo, _ string) *WorkerInfo {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := candidates[i], candidates[j]
+	if b.outstanding.Load() < a.outstanding.Load() {
+		return b
+	}
+	return a
+}
+
+// --- Hash consistente ---
+
+// Reparte por clave sobre un anillo con replicas nodos virtuales por worker:
+// la misma clave va siempre al mismo worker, y al entrar o salir un worker
+// solo cambian de worker las claves de su parte del anillo.
+//
+// El anillo incluye todos los workers registrados y solo se reconstruye cuando
+// cambia el registro. Los que no son candidatos para una petición (inactivos,
+// excluidos en un reintento o sin la ruta) se saltan siguiendo el anillo, así
+// que no obligan a reconstruirlo.
+type consistentHash struct {
+	mu       sync.Mutex
+	replicas int
+	members  string            // URLs del anillo construido, para detectar cambios en el registro
+	ring     []uint32          // Posiciones de los nodos virtuales, ordenadas
+	owners   map[uint32]string // Posición -> URL del worker
+}
+
+func (s *consistentHash) Name() string { return "consistent-hash" }
+
+func (s *consistentHash) Pick(candidates []*WorkerInfo, key string) *WorkerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.build(memberURLs(candidates))
+
+	byURL := make(map[string]*WorkerInfo, len(candidates))
+	for _, wk := range candidates {
+		byURL[wk.URL] = wk
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(s.ring), func(i int) bool { return s.ring[i] >= h })
+	for n := 0; n < len(s.ring); n++ {
+		if wk := byURL[s.owners[s.ring[(start+n)%len(s.ring)]]]; wk != nil {
+			return wk
+		}
+	}
+	return candidates[0]
+}
+
+// Reconstruye el anillo si cambiaron los workers registrados. Requiere tener s.mu.
+func (s *consistentHash) build(urls []string) {
+	members := strings.Join(urls, "\n")
+	if members == s.members {
+		return
+	}
+
+	s.members = members
+	s.ring = s.ring[:0]
+	s.owners = make(map[uint32]string, len(urls)*s.replicas)
+	for _, url := range urls {
+		for r := 0; r < s.replicas; r++ {
+			h := hashKey(url + "#" + strconv.Itoa(r))
+			s.ring = append(s.ring, h)
+			s.owners[h] = url
+		}
+	}
+	sort.Slice(s.ring, func(i, j int) bool { return s.ring[i] < s.ring[j] })
+}
+
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// Cuerpo de respuesta que descuenta la petición en curso del worker al
+// cerrarse y cancela su contexto, si tiene.
+type outstandingBody struct {
+	io.ReadCloser
+	wk     *WorkerInfo
+	cancel func()
+	once   sync.Once
+}
+
+func (b *outstandingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.wk.outstanding.Add(-1)
+		if b.cancel != nil {
+			b.cancel()
+		}
+	})
+	return err
+}
+
+// Peso del worker para el round-robin ponderado (al menos 1).
+func (wk *WorkerInfo) weight() int {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+	return max(wk.Weight, 1)
+}
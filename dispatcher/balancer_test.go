@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestWorkers creates active workers w0..wn-1 with the given weights (nil = 1).
+func newTestWorkers(n int, weights ...int) []*WorkerInfo {
+	pool := make([]*WorkerInfo, n)
+	for i := range pool {
+		pool[i] = &WorkerInfo{URL: fmt.Sprintf("http://w%d:8080", i), State: StateActive}
+		if i < len(weights) {
+			pool[i].Weight = weights[i]
+		}
+	}
+	return pool
+}
+
+// countPicks picks n times and counts the picks per worker URL.
+func countPicks(s Strategy, pool []*WorkerInfo, n int, key func(i int) string) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[s.Pick(pool, key(i)).URL]++
+	}
+	return counts
+}
+
+func noKey(int) string { return "" }
+
+func TestRoundRobinDistribution(t *testing.T) {
+	pool := newTestWorkers(3)
+	counts := countPicks(&roundRobin{}, pool, 300, noKey)
+
+	for _, wk := range pool {
+		if counts[wk.URL] != 100 {
+			t.Errorf("Expected 100 picks for %s, got %d", wk.URL, counts[wk.URL])
+		}
+	}
+}
+
+func TestWeightedRoundRobinDistribution(t *testing.T) {
+	pool := newTestWorkers(3, 1, 2, 3)
+	s := &weightedRoundRobin{current: map[string]int{}}
+
+	// Smooth: within each cycle of 6 picks, no worker gets more than its weight
+	var sequence []string
+	for i := 0; i < 6; i++ {
+		sequence = append(sequence, s.Pick(pool, "").URL)
+	}
+	counts := make(map[string]int)
+	for _, url := range sequence {
+		counts[url]++
+	}
+	for i, wk := range pool {
+		if counts[wk.URL] != i+1 {
+			t.Errorf("Expected %d picks for %s in one cycle, got %d (%v)", i+1, wk.URL, counts[wk.URL], sequence)
+		}
+	}
+	for i := 1; i < len(sequence); i++ {
+		if sequence[i] == sequence[i-1] && sequence[i] != pool[2].URL {
+			t.Errorf("Expected no bursts for light workers, got %v", sequence)
+		}
+	}
+
+	counts = countPicks(s, pool, 600, noKey)
+	for i, wk := range pool {
+		if want := 100 * (i + 1); counts[wk.URL] != want {
+			t.Errorf("Expected %d picks for %s, got %d", want, wk.URL, counts[wk.URL])
+		}
+	}
+}
+
+func TestWeightedRoundRobinPrunesDepartedWorkers(t *testing.T) {
+	pool := newTestWorkers(3)
+	resetWorkers(pool...)
+	defer resetWorkers()
+	s := &weightedRoundRobin{current: map[string]int{}}
+	countPicks(s, pool, 6, noKey)
+
+	// The third worker leaves the registry
+	resetWorkers(pool[:2]...)
+	s.Pick(pool[:2], "")
+
+	if _, ok := s.current[pool[2].URL]; ok || len(s.current) != 2 {
+		t.Errorf("Expected the departed worker to be pruned, got %v", s.current)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	pool := newTestWorkers(3)
+	pool[0].outstanding.Store(5)
+	pool[1].outstanding.Store(1)
+	pool[2].outstanding.Store(3)
+
+	if got := (leastOutstanding{}).Pick(pool, ""); got != pool[1] {
+		t.Errorf("Expected %s, got %s", pool[1].URL, got.URL)
+	}
+}
+
+func TestPowerOfTwoDistribution(t *testing.T) {
+	pool := newTestWorkers(3)
+
+	// Equal load: roughly uniform
+	counts := countPicks(powerOfTwo{}, pool, 3000, noKey)
+	for _, wk := range pool {
+		if counts[wk.URL] < 800 || counts[wk.URL] > 1200 {
+			t.Errorf("Expected about 1000 picks for %s, got %d", wk.URL, counts[wk.URL])
+		}
+	}
+
+	// The most loaded worker only wins when paired with itself, which never happens
+	pool[0].outstanding.Store(10)
+	counts = countPicks(powerOfTwo{}, pool, 3000, noKey)
+	if counts[pool[0].URL] != 0 {
+		t.Errorf("Expected the most loaded worker never to be picked, got %d", counts[pool[0].URL])
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	pool := newTestWorkers(3)
+	s := &consistentHash{replicas: 100}
+	key := func(i int) string { return fmt.Sprintf("/files/%d", i) }
+
+	// Same key, same worker
+	before := make(map[string]string)
+	for i := 0; i < 3000; i++ {
+		before[key(i)] = s.Pick(pool, key(i)).URL
+		if again := s.Pick(pool, key(i)).URL; again != before[key(i)] {
+			t.Fatalf("Expected key %s to stay on %s, got %s", key(i), before[key(i)], again)
+		}
+	}
+
+	// Roughly balanced
+	counts := make(map[string]int)
+	for _, url := range before {
+		counts[url]++
+	}
+	for _, wk := range pool {
+		if counts[wk.URL] < 600 {
+			t.Errorf("Expected a fair share of keys for %s, got %d", wk.URL, counts[wk.URL])
+		}
+	}
+
+	// Removing a worker only moves its own keys
+	removed := pool[1].URL
+	for i := 0; i < 3000; i++ {
+		after := s.Pick([]*WorkerInfo{pool[0], pool[2]}, key(i)).URL
+		if before[key(i)] != removed && after != before[key(i)] {
+			t.Fatalf("Expected key %s to stay on %s, moved to %s", key(i), before[key(i)], after)
+		}
+	}
+}
+
+func TestConsistentHashSkipsCandidatesWithoutRebuilding(t *testing.T) {
+	pool := newTestWorkers(3)
+	resetWorkers(pool...)
+	defer resetWorkers()
+	s := &consistentHash{replicas: 100}
+	s.Pick(pool, "")
+	members := s.members
+
+	// A registered worker that is not a candidate is skipped on the ring
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("/files/%d", i)
+		owner := s.Pick(pool, key)
+		candidates := []*WorkerInfo{}
+		for _, wk := range pool {
+			if wk != owner {
+				candidates = append(candidates, wk)
+			}
+		}
+		if got := s.Pick(candidates, key); got == owner {
+			t.Fatalf("Expected key %s to skip the excluded %s", key, owner.URL)
+		}
+	}
+
+	if s.members != members || len(s.ring) != 3*s.replicas {
+		t.Errorf("Expected the ring to keep all registered workers, got %d positions", len(s.ring))
+	}
+}
+
+func TestPickWorkerSkipsExcludedAndInactive(t *testing.T) {
+	pool := newTestWorkers(3)
+	pool[2].State = StateSuspect
+	resetWorkers(pool...)
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	for i := 0; i < 10; i++ {
+		if got := PickWorker("", "", "", map[string]bool{pool[0].URL: true}); got != pool[1] {
+			t.Fatalf("Expected %s, got %v", pool[1].URL, got)
+		}
+	}
+	if got := PickWorker("", "", "", map[string]bool{pool[0].URL: true, pool[1].URL: true}); got != nil {
+		t.Errorf("Expected no worker, got %s", got.URL)
+	}
+}
+
+func TestStrategyHandler(t *testing.T) {
+	defer SetStrategy(&roundRobin{})
+
+	req := httptest.NewRequest("POST", "/admin/strategy", strings.NewReader(`{"strategy":"least-outstanding"}`))
+	rec := httptest.NewRecorder()
+	StrategyHandler(rec, req)
+
+	if rec.Code != http.StatusOK || CurrentStrategy().Name() != "least-outstanding" {
+		t.Errorf("Expected strategy to switch, got %d %s", rec.Code, CurrentStrategy().Name())
+	}
+
+	req = httptest.NewRequest("POST", "/admin/strategy", strings.NewReader(`{"strategy":"random"}`))
+	rec = httptest.NewRecorder()
+	StrategyHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest || CurrentStrategy().Name() != "least-outstanding" {
+		t.Errorf("Expected unknown strategy to be rejected, got %d %s", rec.Code, CurrentStrategy().Name())
+	}
+}
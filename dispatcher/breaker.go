@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Estado del circuit breaker de un worker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Las peticiones pasan y se miden
+	BreakerOpen     BreakerState = "open"      // El worker no recibe peticiones hasta OpenTimeout
+	BreakerHalfOpen BreakerState = "half-open" // Pasan HalfOpenTrials peticiones de prueba
+)
+
+// Política de los circuit breakers de los workers.
+type BreakerPolicy struct {
+	Window         time.Duration // Ventana deslizante en la que se miden las peticiones
+	Buckets        int           // Tramos en que se divide la ventana
+	MinRequests    int           // Peticiones mínimas en la ventana para poder abrir
+	ErrorRate      float64       // Fracción de errores (conexión o 5xx) que abre el circuito
+	SlowCall       time.Duration // Latencia a partir de la cual una petición es lenta
+	SlowRate       float64       // Fracción de peticiones lentas que abre el circuito (0 = no se mira)
+	OpenTimeout    time.Duration // Tiempo abierto antes de pasar a semiabierto
+	HalfOpenTrials int           // Peticiones de prueba en semiabierto; si todas van bien, se cierra
+}
+
+// Devuelve la política predeterminada.
+func DefaultBreakerPolicy() BreakerPolicy {
+	return BreakerPolicy{
+		Window:         10 * time.Second,
+		Buckets:        10,
+		MinRequests:    10,
+		ErrorRate:      0.5,
+		SlowCall:       5 * time.Second,
+		SlowRate:       0, // Hay rutas lentas a propósito (/simulate, /sleep, /loadtest)
+		OpenTimeout:    10 * time.Second,
+		HalfOpenTrials: 3,
+	}
+}
+
+// Política activa; main la carga del entorno con LoadBreakerPolicy.
+var breakerPolicy = DefaultBreakerPolicy()
+
+// Carga la política desde las variables de entorno BREAKER_WINDOW, BREAKER_SLOW_CALL,
+// BREAKER_OPEN_TIMEOUT (duraciones), BREAKER_MIN_REQUESTS, BREAKER_TRIALS (enteros),
+// BREAKER_ERROR_RATE (fracción mayor que 0 y hasta 1) y BREAKER_SLOW_RATE
+// (fracción entre 0 y 1). La ventana debe durar al menos un nanosegundo por tramo.
+// Las variables ausentes conservan el valor predeterminado.
+func LoadBreakerPolicy() (BreakerPolicy, error) {
+	policy := DefaultBreakerPolicy()
+
+	durations := map[string]*time.Duration{
+		"BREAKER_WINDOW":       &policy.Window,
+		"BREAKER_SLOW_CALL":    &policy.SlowCall,
+		"BREAKER_OPEN_TIMEOUT": &policy.OpenTimeout,
+	}
+	for name, target := range durations {
+		if err := envDuration(name, target); err != nil {
+			return policy, err
+		}
+	}
+
+	ints := map[string]*int{
+		"BREAKER_MIN_REQUESTS": &policy.MinRequests,
+		"BREAKER_TRIALS":       &policy.HalfOpenTrials,
+	}
+	for name, target := range ints {
+		if err := envInt(name, target, 1); err != nil {
+			return policy, err
+		}
+	}
+
+	// Con una fracción de errores 0 cualquier petición abriría el circuito al
+	// llegar a MinRequests, así que se exige más que 0; SlowRate 0 lo desactiva
+	if err := envFloat("BREAKER_ERROR_RATE", &policy.ErrorRate, math.SmallestNonzeroFloat64, 1); err != nil {
+		return policy, err
+	}
+	if err := envFloat("BREAKER_SLOW_RATE", &policy.SlowRate, 0, 1); err != nil {
+		return policy, err
+	}
+
+	// Los tramos de la ventana no pueden durar 0
+	if policy.Window < time.Duration(policy.Buckets) {
+		return policy, fmt.Errorf("bad BREAKER_WINDOW: %q", os.Getenv("BREAKER_WINDOW"))
+	}
+
+	return policy, nil
+}
+
+// Peticiones medidas en un tramo de la ventana.
+type breakerBucket struct {
+	start    time.Time
+	requests int
+	failures int
+	slow     int
+}
+
+// Circuit breaker de un worker. El valor cero es un circuito cerrado listo para usar.
+//
+// Cerrado, mide las peticiones en una ventana deslizante y se abre si los errores
+// o las peticiones lentas superan su fracción (con al menos MinRequests).
+// Abierto, rechaza las peticiones durante OpenTimeout y pasa a semiabierto.
+// Semiabierto, deja pasar HalfOpenTrials peticiones de prueba: un fallo lo
+// vuelve a abrir y, si todas van bien, se cierra con la ventana vacía.
+type breaker struct {
+	mu       sync.Mutex
+	state    BreakerState
+	gen      uint64 // Cambia con cada transición de estado
+	buckets  []breakerBucket
+	openedAt time.Time
+	trials   int   // Pruebas concedidas en semiabierto
+	passed   int   // Pruebas correctas en semiabierto
+	opens    int64 // Veces que se abrió desde el arranque
+}
+
+// Permiso que concede allow a una petición; se entrega a record o a release.
+// Solo cuenta en el mismo estado (misma generación) en que se concedió: el
+// resultado de una petición de antes de abrirse no es una prueba en semiabierto.
+type breakerTicket struct {
+	gen   uint64
+	trial bool // Una de las pruebas de semiabierto
+}
+
+// Métricas del circuit breaker en un momento dado.
+type BreakerStats struct {
+	State    BreakerState `json:"state"`
+	Requests int          `json:"requests"` // Peticiones en la ventana
+	Failures int          `json:"failures"` // Errores en la ventana
+	Slow     int          `json:"slow"`     // Peticiones lentas en la ventana
+	Opens    int64        `json:"opens"`    // Veces que se abrió desde el arranque
+}
+
+// Estado actual; pasa de abierto a semiabierto si venció OpenTimeout. Requiere b.mu.
+func (b *breaker) current(now time.Time) BreakerState {
+	switch b.state {
+	case "":
+		b.state = BreakerClosed
+	case BreakerOpen:
+		if !now.Before(b.openedAt.Add(breakerPolicy.OpenTimeout)) {
+			b.state = BreakerHalfOpen
+			b.gen++
+			b.trials, b.passed = 0, 0
+		}
+	}
+	return b.state
+}
+
+// Indica si el worker puede recibir una petición, sin reservarla.
+func (b *breaker) ready(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current(now) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return b.trials < breakerPolicy.HalfOpenTrials
+	}
+	return true
+}
+
+// Reserva una petición: siempre con el circuito cerrado, una de las pruebas
+// en semiabierto y ninguna abierto. Cada petición concedida debe terminar en
+// record o en release con su permiso.
+func (b *breaker) allow(now time.Time) (breakerTicket, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ticket := breakerTicket{gen: b.gen}
+	switch b.current(now) {
+	case BreakerOpen:
+		return ticket, false
+	case BreakerHalfOpen:
+		if b.trials >= breakerPolicy.HalfOpenTrials {
+			return ticket, false
+		}
+		b.trials++
+		ticket = breakerTicket{gen: b.gen, trial: true}
+	}
+	return ticket, true
+}
+
+// Devuelve una petición concedida por allow cuyo resultado no se medirá
+// (no llegó a enviarse o el dispatcher la canceló).
+func (b *breaker) release(ticket breakerTicket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.trial && ticket.gen == b.gen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+// Registra el resultado de una petición concedida por allow. Se ignora si el
+// circuito cambió de estado desde entonces.
+func (b *breaker) record(ticket breakerTicket, ok bool, latency time.Duration, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slow := breakerPolicy.SlowCall > 0 && latency >= breakerPolicy.SlowCall
+
+	state := b.current(now)
+	if ticket.gen != b.gen {
+		// Respuesta tardía de un estado anterior
+		return
+	}
+
+	switch state {
+	case BreakerOpen:
+		return
+	case BreakerHalfOpen:
+		if !ticket.trial {
+			return
+		}
+		if !ok || slow {
+			b.open(now)
+			return
+		}
+		b.passed++
+		if b.passed >= breakerPolicy.HalfOpenTrials {
+			b.state = BreakerClosed
+			b.gen++
+			b.buckets = nil
+		}
+		return
+	}
+
+	bucket := b.bucket(now)
+	bucket.requests++
+	if !ok {
+		bucket.failures++
+	}
+	if slow {
+		bucket.slow++
+	}
+
+	requests, failures, slowCalls := b.window(now)
+	if requests < breakerPolicy.MinRequests {
+		return
+	}
+	if float64(failures) >= breakerPolicy.ErrorRate*float64(requests) ||
+		(breakerPolicy.SlowRate > 0 && float64(slowCalls) >= breakerPolicy.SlowRate*float64(requests)) {
+		b.open(now)
+	}
+}
+
+// Abre el circuito. Requiere b.mu.
+func (b *breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.gen++
+	b.openedAt = now
+	b.opens++
+}
+
+// Devuelve el tramo de la ventana correspondiente a now, vaciándolo si
+// pertenecía a una vuelta anterior. Requiere b.mu.
+func (b *breaker) bucket(now time.Time) *breakerBucket {
+	if len(b.buckets) != breakerPolicy.Buckets {
+		b.buckets = make([]breakerBucket, breakerPolicy.Buckets)
+	}
+
+	width := breakerPolicy.Window / time.Duration(breakerPolicy.Buckets)
+	start := now.Truncate(width)
+	bucket := &b.buckets[int(start.UnixNano()/int64(width))%len(b.buckets)]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// Suma las peticiones de los tramos que siguen dentro de la ventana. Requiere b.mu.
+func (b *breaker) window(now time.Time) (requests, failures, slow int) {
+	since := now.Add(-breakerPolicy.Window)
+	for _, bucket := range b.buckets {
+		if bucket.start.After(since) {
+			requests += bucket.requests
+			failures += bucket.failures
+			slow += bucket.slow
+		}
+	}
+	return requests, failures, slow
+}
+
+// Devuelve las métricas del circuit breaker.
+func (b *breaker) stats(now time.Time) BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	requests, failures, slow := b.window(now)
+	return BreakerStats{
+		State:    b.current(now),
+		Requests: requests,
+		Failures: failures,
+		Slow:     slow,
+		Opens:    b.opens,
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withBreakerPolicy replaces the breaker policy for a test.
+func withBreakerPolicy(t *testing.T) {
+	t.Helper()
+	policy := DefaultBreakerPolicy()
+	policy.MinRequests = 4
+	policy.ErrorRate = 0.5
+	policy.SlowCall = time.Second
+	policy.SlowRate = 0.75
+	policy.OpenTimeout = 10 * time.Second
+	policy.HalfOpenTrials = 2
+	withPolicy(t, &breakerPolicy, policy)
+}
+
+func TestBreakerOpensOnRates(t *testing.T) {
+	withBreakerPolicy(t)
+
+	tests := []struct {
+		name      string
+		results   []bool
+		latency   time.Duration
+		wantState BreakerState
+	}{
+		{"healthy", []bool{true, true, true, false}, 0, BreakerClosed},
+		{"below min requests", []bool{false, false, false}, 0, BreakerClosed},
+		{"error rate", []bool{true, false, true, false}, 0, BreakerOpen},
+		{"slow calls", []bool{true, true, true, true}, 2 * time.Second, BreakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b breaker
+			now := time.Now()
+			for _, ok := range tt.results {
+				b.record(breakerTicket{}, ok, tt.latency, now)
+			}
+			if got := b.stats(now).State; got != tt.wantState {
+				t.Errorf("Expected %s, got %s", tt.wantState, got)
+			}
+		})
+	}
+}
+
+func TestBreakerWindowSlides(t *testing.T) {
+	withBreakerPolicy(t)
+
+	var b breaker
+	now := time.Now()
+	b.record(breakerTicket{}, false, 0, now)
+	b.record(breakerTicket{}, false, 0, now)
+	b.record(breakerTicket{}, false, 0, now)
+
+	// The old failures leave the window, so new successes keep it closed
+	later := now.Add(breakerPolicy.Window + time.Second)
+	for i := 0; i < 4; i++ {
+		b.record(breakerTicket{}, i > 0, 0, later)
+	}
+
+	stats := b.stats(later)
+	if stats.State != BreakerClosed || stats.Requests != 4 || stats.Failures != 1 {
+		t.Errorf("Expected closed with 4 requests and 1 failure, got %+v", stats)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	withBreakerPolicy(t)
+
+	var b breaker
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		b.record(breakerTicket{}, false, 0, now)
+	}
+	if _, ok := b.allow(now); ok {
+		t.Fatalf("Expected open breaker to reject requests")
+	}
+
+	// After the open timeout only HalfOpenTrials requests pass
+	now = now.Add(breakerPolicy.OpenTimeout)
+	first, ok1 := b.allow(now)
+	second, ok2 := b.allow(now)
+	if !ok1 || !ok2 {
+		t.Fatalf("Expected half-open breaker to allow trial requests")
+	}
+	if _, ok := b.allow(now); ok || b.ready(now) {
+		t.Errorf("Expected no more trials than HalfOpenTrials")
+	}
+
+	// A failed trial opens it again
+	b.record(first, true, 0, now)
+	b.record(second, false, 0, now)
+	if stats := b.stats(now); stats.State != BreakerOpen || stats.Opens != 2 {
+		t.Fatalf("Expected breaker to reopen, got %+v", stats)
+	}
+
+	// Successful trials close it
+	now = now.Add(breakerPolicy.OpenTimeout)
+	for i := 0; i < breakerPolicy.HalfOpenTrials; i++ {
+		ticket, _ := b.allow(now)
+		b.record(ticket, true, 0, now)
+	}
+	if got := b.stats(now).State; got != BreakerClosed {
+		t.Errorf("Expected %s, got %s", BreakerClosed, got)
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	withBreakerPolicy(t)
+
+	var b breaker
+	now := time.Now()
+	late, _ := b.allow(now)
+	for i := 0; i < 4; i++ {
+		b.record(breakerTicket{}, false, 0, now)
+	}
+
+	// Results of requests admitted before the breaker opened are not trials
+	now = now.Add(breakerPolicy.OpenTimeout)
+	trial, _ := b.allow(now)
+	for i := 0; i < breakerPolicy.HalfOpenTrials; i++ {
+		b.record(late, true, 0, now)
+	}
+	if got := b.stats(now).State; got != BreakerHalfOpen {
+		t.Fatalf("Expected stale successes to keep it %s, got %s", BreakerHalfOpen, got)
+	}
+	b.record(late, false, 0, now)
+	if got := b.stats(now).State; got != BreakerHalfOpen {
+		t.Fatalf("Expected a stale failure to keep it %s, got %s", BreakerHalfOpen, got)
+	}
+
+	// Releasing a stale ticket does not return a trial slot
+	b.release(late)
+	b.allow(now)
+	if _, ok := b.allow(now); ok {
+		t.Errorf("Expected a stale release to leave the trials taken")
+	}
+	b.record(trial, true, 0, now)
+}
+
+func TestDefaultBreakerIgnoresSlowCalls(t *testing.T) {
+	var b breaker
+	now := time.Now()
+	for i := 0; i < DefaultBreakerPolicy().MinRequests; i++ {
+		b.record(breakerTicket{}, true, time.Minute, now)
+	}
+	if got := b.stats(now).State; got != BreakerClosed {
+		t.Errorf("Expected slow successes to keep it %s, got %s", BreakerClosed, got)
+	}
+}
+
+func TestLoadBreakerPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"defaults", nil, false},
+		{"slow rate disabled", map[string]string{"BREAKER_SLOW_RATE": "0"}, false},
+		{"error rate of one", map[string]string{"BREAKER_ERROR_RATE": "1"}, false},
+		{"zero error rate", map[string]string{"BREAKER_ERROR_RATE": "0"}, true},
+		{"error rate above one", map[string]string{"BREAKER_ERROR_RATE": "1.5"}, true},
+		{"window at one nanosecond per bucket", map[string]string{"BREAKER_WINDOW": "10ns"}, false},
+		{"window shorter than its buckets", map[string]string{"BREAKER_WINDOW": "5ns"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if _, err := LoadBreakerPolicy(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDoRequestSkipsOpenBreaker(t *testing.T) {
+	withBreakerPolicy(t)
+
+	hits := make(map[string]int)
+	newWorker := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name]++
+		}))
+	}
+	broken, healthy := newWorker("broken"), newWorker("healthy")
+	defer broken.Close()
+	defer healthy.Close()
+
+	pool := []*WorkerInfo{
+		{URL: broken.URL, State: StateActive},
+		{URL: healthy.URL, State: StateActive},
+	}
+	for i := 0; i < 4; i++ {
+		pool[0].breaker.record(breakerTicket{}, false, 0, time.Now())
+	}
+	resetWorkers(pool...)
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	for i := 0; i < 4; i++ {
+		resp, err := DoRequestWithRetry(context.Background(), "GET", "/ping", nil, http.Header{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if hits["broken"] != 0 || hits["healthy"] != 4 {
+		t.Errorf("Expected all requests on the healthy worker, got %v", hits)
+	}
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	want := `dispatcher_worker_breaker_state{worker="` + broken.URL + `"} 2`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected metrics to contain %q, got:\n%s", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Convierte las capacidades que anuncia un worker ("GET /files/{name}", ...)
+// en rutas con los mismos patrones que su enrutador.
+func parseCapabilities(capabilities []string) ([]core.Handler, error) {
+	if len(capabilities) == 0 {
+		return nil, nil
+	}
+
+	routes := make([]core.Handler, 0, len(capabilities))
+	for _, capability := range capabilities {
+		fields := strings.Fields(capability)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("bad capability: %q", capability)
+		}
+		routes = append(routes, core.Handler{Method: strings.ToUpper(fields[0]), Path: fields[1]})
+	}
+	return routes, nil
+}
+
+// Indica si el worker atiende method en path, con las mismas reglas que su
+// enrutador (HEAD con GET, OPTIONS siempre). Un worker que no anunció
+// capacidades, como los registrados a mano, se considera capaz de todo.
+// Un method vacío no filtra.
+func (wk *WorkerInfo) serves(method, path string) bool {
+	if method == "" {
+		return true
+	}
+
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	if wk.routes == nil {
+		return true
+	}
+	return slices.Contains(core.AllowedMethods(wk.routes, path), method)
+}
+
+// Métodos que el worker atiende en path (nil si no atiende la ruta o no
+// anunció capacidades).
+func (wk *WorkerInfo) allowedMethods(path string) []string {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+	return core.AllowedMethods(wk.routes, path)
+}
+
+// Workers activos que atienden method en path y cuyo circuit breaker está
+// dispuesto a recibir peticiones.
+func ServingWorkers(method, path string) []*WorkerInfo {
+	now := time.Now()
+	serving := make([]*WorkerInfo, 0)
+	for _, wk := range GetActiveWorkers() {
+		if wk.serves(method, path) && wk.breaker.ready(now) {
+			serving = append(serving, wk)
+		}
+	}
+	return serving
+}
+
+// Comprueba si algún worker activo puede atender la petición. Devuelve 0 si
+// alguno puede; 405 y los métodos permitidos si la ruta solo se atiende con
+// otros métodos; 404 si ningún worker la atiende; 503 si no hay workers activos.
+func routeStatus(method, path string) (int, []string) {
+	active := GetActiveWorkers()
+	if len(active) == 0 {
+		return http.StatusServiceUnavailable, nil
+	}
+
+	methods := make(map[string]bool)
+	for _, wk := range active {
+		if wk.serves(method, path) {
+			return 0, nil
+		}
+		for _, m := range wk.allowedMethods(path) {
+			methods[m] = true
+		}
+	}
+
+	if len(methods) == 0 {
+		return http.StatusNotFound, nil
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for m := range methods {
+		allowed = append(allowed, m)
+	}
+	sort.Strings(allowed)
+	return http.StatusMethodNotAllowed, allowed
+}
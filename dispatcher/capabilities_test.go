@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capableWorker creates an active worker announcing the given capabilities.
+func capableWorker(t *testing.T, url string, capabilities ...string) *WorkerInfo {
+	t.Helper()
+	routes, err := parseCapabilities(capabilities)
+	if err != nil {
+		t.Fatalf("Failed to parse capabilities: %v", err)
+	}
+	return &WorkerInfo{URL: url, State: StateActive, Capabilities: capabilities, routes: routes}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	for _, bad := range []string{"GET", "/ping", "GET ping", "GET /a /b"} {
+		if _, err := parseCapabilities([]string{bad}); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if routes, err := parseCapabilities(nil); routes != nil || err != nil {
+		t.Errorf("Expected no routes, got %v %v", routes, err)
+	}
+}
+
+func TestWorkerServes(t *testing.T) {
+	wk := capableWorker(t, "http://w0", "GET /fibonacci", "DELETE /files/{name}", "GET /static/*")
+	anything := &WorkerInfo{URL: "http://w1", State: StateActive}
+
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{"GET", "/fibonacci", true},
+		{"HEAD", "/fibonacci", true},
+		{"OPTIONS", "/fibonacci", true},
+		{"POST", "/fibonacci", false},
+		{"DELETE", "/files/a.txt", true},
+		{"DELETE", "/files", false},
+		{"GET", "/static/css/site.css", true},
+		{"GET", "/hash", false},
+	}
+
+	for _, tt := range tests {
+		if got := wk.serves(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: expected %v, got %v", tt.method, tt.path, tt.want, got)
+		}
+		if !anything.serves(tt.method, tt.path) {
+			t.Errorf("%s %s: expected worker without capabilities to serve it", tt.method, tt.path)
+		}
+	}
+}
+
+func TestPickWorkerOnlyCapable(t *testing.T) {
+	pool := []*WorkerInfo{
+		capableWorker(t, "http://w0", "GET /fibonacci"),
+		capableWorker(t, "http://w1", "POST /matrix/part"),
+	}
+	resetWorkers(pool...)
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	for i := 0; i < 4; i++ {
+		if got := PickWorker("POST", "/matrix/part", "", nil); got != pool[1] {
+			t.Fatalf("Expected %s, got %v", pool[1].URL, got)
+		}
+	}
+	if got := PickWorker("GET", "/hash", "", nil); got != nil {
+		t.Errorf("Expected no worker, got %s", got.URL)
+	}
+}
+
+func TestProxyHandlerRouteStatus(t *testing.T) {
+	defer resetWorkers()
+
+	tests := []struct {
+		name   string
+		pool   []*WorkerInfo
+		method string
+		path   string
+		want   int
+		allow  string
+	}{
+		{"no active workers", nil, "GET", "/fibonacci", http.StatusServiceUnavailable, ""},
+		{"unknown path", []*WorkerInfo{capableWorker(t, "http://w0", "GET /fibonacci")}, "GET", "/hash", http.StatusNotFound, ""},
+		{"wrong method", []*WorkerInfo{
+			capableWorker(t, "http://w0", "GET /files/{name}"),
+			capableWorker(t, "http://w1", "DELETE /files/{name}"),
+		}, "POST", "/files/a", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetWorkers(tt.pool...)
+
+			rec := httptest.NewRecorder()
+			ProxyHandler(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader("")))
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Expected Allow %q, got %q", tt.allow, got)
+			}
+		})
+	}
+}
+
+func TestProxyHandlerForwardsToCapableWorker(t *testing.T) {
+	hashWorker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hash"))
+	}))
+	defer hashWorker.Close()
+	otherWorker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to a worker without /hash: %s", r.URL)
+	}))
+	defer otherWorker.Close()
+
+	resetWorkers(
+		capableWorker(t, otherWorker.URL, "GET /fibonacci"),
+		capableWorker(t, hashWorker.URL, "GET /hash"),
+	)
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		ProxyHandler(rec, httptest.NewRequest("GET", "/hash?text=a", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "hash" {
+			t.Errorf("Expected 200 hash, got %d %q", rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Plazo máximo de una petición de un cliente (0 = sin límite); main lo carga
+// de REQUEST_TIMEOUT con LoadRequestTimeout.
+var requestTimeout time.Duration
+
+// Carga el plazo máximo de las peticiones de la variable de entorno
+// REQUEST_TIMEOUT (una duración, como "30s"). Sin ella no hay límite.
+func LoadRequestTimeout() (time.Duration, error) {
+	value := os.Getenv("REQUEST_TIMEOUT")
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("bad REQUEST_TIMEOUT: %q", value)
+	}
+	return d, nil
+}
+
+// Devuelve el contexto con el que se atiende la petición de un cliente: se
+// cancela si el cliente se desconecta y vence con el plazo de su cabecera
+// X-Request-Deadline o tras requestTimeout, lo que llegue antes. send
+// propaga ese plazo a los workers en la misma cabecera.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	var deadline time.Time
+	if value := r.Header.Get(core.DeadlineHeader); value != "" {
+		d, err := core.ParseDeadline(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %q", core.DeadlineHeader, value)
+		}
+		deadline = d
+	}
+
+	if requestTimeout > 0 {
+		if limit := time.Now().Add(requestTimeout); deadline.IsZero() || limit.Before(deadline) {
+			deadline = limit
+		}
+	}
+
+	if deadline.IsZero() {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	return ctx, cancel, nil
+}
+
+// Responde al cliente el error de una petición reenviada: 504 si venció su
+// plazo, nada si el cliente ya se fue y 502 en otro caso.
+func writeProxyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		// El cliente cerró la conexión: nadie leerá la respuesta
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// waitingWorker starts a worker that waits up to 2s, reporting when its
+// request is cancelled, and records the deadline header it received.
+func waitingWorker(t *testing.T, deadline *atomic.Value, cancelled chan<- struct{}) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline.Store(r.Header.Get(core.DeadlineHeader))
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-time.After(2 * time.Second):
+			w.Write([]byte("done"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	resetWorkers(&WorkerInfo{URL: server.URL, State: StateActive})
+	t.Cleanup(func() { resetWorkers() })
+	SetStrategy(&roundRobin{})
+}
+
+func TestProxyPropagatesDeadline(t *testing.T) {
+	withRetryPolicy(t)
+	var deadline atomic.Value
+	cancelled := make(chan struct{}, 1)
+	waitingWorker(t, &deadline, cancelled)
+
+	want := core.FormatDeadline(time.Now().Add(50 * time.Millisecond))
+	req := httptest.NewRequest("GET", "/simulate?seconds=600&task=t", nil)
+	req.Header.Set(core.DeadlineHeader, want)
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	ProxyHandler(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to stop at the deadline, took %v", elapsed)
+	}
+	if got := deadline.Load(); got != want {
+		t.Errorf("Expected the worker to receive deadline %q, got %v", want, got)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the worker request to be cancelled")
+	}
+}
+
+func TestProxyCancelsWorkerOnClientDisconnect(t *testing.T) {
+	withRetryPolicy(t)
+	var deadline atomic.Value
+	cancelled := make(chan struct{}, 1)
+	waitingWorker(t, &deadline, cancelled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/sleep?seconds=600", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		ProxyHandler(rec, req)
+		close(done)
+	}()
+
+	// The client goes away while the worker is still sleeping
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the worker request to be cancelled")
+	}
+	<-done
+	if got := deadline.Load(); got != "" {
+		t.Errorf("Expected no deadline header without a deadline, got %v", got)
+	}
+}
+
+func TestProxyRejectsInvalidDeadline(t *testing.T) {
+	req := httptest.NewRequest("GET", "/hash", nil)
+	req.Header.Set(core.DeadlineHeader, "tomorrow")
+	rec := httptest.NewRecorder()
+
+	ProxyHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestRequestTimeoutLimitsDeadline(t *testing.T) {
+	withPolicy(t, &requestTimeout, time.Minute)
+
+	tests := []struct {
+		name   string
+		header time.Time
+		want   time.Duration
+	}{
+		{"no header", time.Time{}, time.Minute},
+		{"earlier header", time.Now().Add(time.Second), time.Second},
+		{"later header", time.Now().Add(time.Hour), time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/hash", nil)
+			if !tt.header.IsZero() {
+				req.Header.Set(core.DeadlineHeader, core.FormatDeadline(tt.header))
+			}
+
+			ctx, cancel, err := requestContext(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if remaining := time.Until(deadline); !ok || remaining > tt.want || remaining < tt.want-time.Second {
+				t.Errorf("Expected a deadline in about %v, got %v", tt.want, remaining)
+			}
+		})
+	}
+}
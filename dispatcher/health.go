@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Política de health-checks del dispatcher.
+type HealthPolicy struct {
+	Interval         time.Duration // Tiempo entre rondas de health-checks
+	Timeout          time.Duration // Tiempo máximo de cada sondeo
+	FailureThreshold int           // Fallos seguidos para que un worker activo pase a sospechoso
+	SuccessThreshold int           // Éxitos seguidos para que un sospechoso vuelva a activo
+	MaxBackoff       time.Duration // Espera máxima entre sondeos a un worker muerto
+
+	// Sondeo profundo: exige que el worker responda /status sin superar estos
+	// límites (0 = sin límite). Sin él, /status solo aporta la carga del worker.
+	DeepProbe     bool
+	MaxGoroutines int // Goroutines del proceso del worker
+	MaxQueueDepth int // Conexiones esperando en la cola del worker
+}
+
+// Devuelve la política predeterminada.
+func DefaultHealthPolicy() HealthPolicy {
+	return HealthPolicy{
+		Interval:         5 * time.Second,
+		Timeout:          2 * time.Second,
+		FailureThreshold: 3,
+		SuccessThreshold: 2,
+		MaxBackoff:       time.Minute,
+	}
+}
+
+// Política activa; main la carga del entorno con LoadHealthPolicy.
+var healthPolicy = DefaultHealthPolicy()
+
+// Carga la política desde las variables de entorno HEALTH_INTERVAL, HEALTH_TIMEOUT,
+// HEALTH_MAX_BACKOFF (duraciones como "5s"), HEALTH_FAILURES, HEALTH_SUCCESSES,
+// HEALTH_MAX_GOROUTINES, HEALTH_MAX_QUEUE (enteros) y HEALTH_PROBE ("ping" o "status").
+// Las variables ausentes conservan el valor predeterminado.
+func LoadHealthPolicy() (HealthPolicy, error) {
+	policy := DefaultHealthPolicy()
+
+	durations := map[string]*time.Duration{
+		"HEALTH_INTERVAL":    &policy.Interval,
+		"HEALTH_TIMEOUT":     &policy.Timeout,
+		"HEALTH_MAX_BACKOFF": &policy.MaxBackoff,
+	}
+	for name, target := range durations {
+		if err := envDuration(name, target); err != nil {
+			return policy, err
+		}
+	}
+
+	ints := map[string]*int{
+		"HEALTH_FAILURES":       &policy.FailureThreshold,
+		"HEALTH_SUCCESSES":      &policy.SuccessThreshold,
+		"HEALTH_MAX_GOROUTINES": &policy.MaxGoroutines,
+		"HEALTH_MAX_QUEUE":      &policy.MaxQueueDepth,
+	}
+	for name, target := range ints {
+		if err := envInt(name, target, 0); err != nil {
+			return policy, err
+		}
+	}
+
+	switch probe := os.Getenv("HEALTH_PROBE"); probe {
+	case "", "ping":
+	case "status":
+		policy.DeepProbe = true
+	default:
+		return policy, fmt.Errorf("bad HEALTH_PROBE: %q", probe)
+	}
+
+	return policy, nil
+}
+
+// Espera antes del siguiente sondeo a un worker muerto: Interval duplicado
+// por cada sondeo que no lo recuperó, hasta MaxBackoff.
+func (policy HealthPolicy) backoff(deadProbes int) time.Duration {
+	wait := policy.Interval
+	for i := 0; i < deadProbes && wait < policy.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, policy.MaxBackoff)
+}
+
+// Sondea el worker según la política. Consulta /status para obtener la carga
+// que reporta el worker y, con DeepProbe, exige que no supere los límites.
+// Un worker sin /status se sondea con /ping, y ni este ni uno cuyo /status no
+// se entiende reportan carga (load nil).
+func (policy HealthPolicy) check(url string) (*WorkerLoad, error) {
+	resp, err := healthClient.Get(url + "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && !policy.DeepProbe {
+		return nil, ping(url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+
+	// Sin sondeo profundo, un /status que no se entiende basta como señal de vida
+	var status workerStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		if !policy.DeepProbe {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("status: %w", err)
+	}
+	load := status.load(time.Now())
+
+	if !policy.DeepProbe {
+		return &load, nil
+	}
+	if policy.MaxGoroutines > 0 && status.Goroutines > policy.MaxGoroutines {
+		return &load, fmt.Errorf("too many goroutines: %d", status.Goroutines)
+	}
+	if policy.MaxQueueDepth > 0 && status.Server.QueueDepth > policy.MaxQueueDepth {
+		return &load, fmt.Errorf("queue too deep: %d", status.Server.QueueDepth)
+	}
+
+	return &load, nil
+}
+
+// Sondea el worker con /ping.
+func ping(url string) error {
+	resp, err := healthClient.Get(url + "/ping")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping: %s", resp.Status)
+	}
+	return nil
+}
+
+// Indica si toca sondear el worker: los que se están drenando no se sondean
+// y los muertos solo cuando vence su espera.
+func (wk *WorkerInfo) dueForProbe(now time.Time) bool {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	switch wk.State {
+	case StateDraining:
+		return false
+	case StateDead:
+		return !now.Before(wk.nextProbe)
+	}
+	return true
+}
+
+// Sondea el worker, guarda la carga que reporte y aplica el resultado.
+func probeWorker(wk *WorkerInfo) {
+	load, err := healthPolicy.check(wk.URL)
+	if load != nil {
+		wk.setLoad(*load)
+	}
+	wk.recordResult(err == nil, time.Now())
+}
+
+// Aplica el resultado de un sondeo al estado del worker,
+// con histéresis: un worker activo solo pasa a sospechoso tras FailureThreshold
+// fallos seguidos y un sospechoso solo vuelve a activo tras SuccessThreshold
+// éxitos seguidos. Un worker nuevo se activa con su primer sondeo correcto.
+// El lease lo renuevan el registro y los latidos; un sondeo correcto solo lo
+// renueva si el worker no envía latidos, como los registrados a mano. Por eso
+// solo estos vuelven de muertos a sospechosos con un sondeo correcto: los que
+// envían latidos reciben 404 en el siguiente y vuelven a registrarse. Mientras
+// tanto, cada sondeo a un worker muerto alarga la espera hasta el siguiente.
+// Los sondeos no afectan a un worker drenándose. Los fallos de las peticiones
+// no cuentan aquí, sino en el circuit breaker del worker.
+func (wk *WorkerInfo) recordResult(ok bool, now time.Time) {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	switch {
+	case wk.State == StateDraining:
+		return
+	case wk.State == StateDead && ok && !wk.heartbeats:
+		wk.State = StateSuspect
+		wk.failures, wk.successes = 0, 1
+		wk.deadProbes = 0
+		wk.renewLease(now)
+		return
+	case wk.State == StateDead:
+		wk.deadProbes++
+		wk.nextProbe = now.Add(healthPolicy.backoff(wk.deadProbes))
+		return
+	}
+
+	if !ok {
+		wk.successes = 0
+		wk.failures++
+
+		if wk.State == StateActive && wk.failures >= healthPolicy.FailureThreshold {
+			wk.State = StateSuspect
+		}
+		return
+	}
+
+	wk.failures = 0
+	wk.successes++
+	if !wk.heartbeats {
+		wk.renewLease(now)
+	}
+
+	switch wk.State {
+	case StateJoining:
+		wk.State = StateActive
+	case StateSuspect:
+		if wk.successes >= healthPolicy.SuccessThreshold {
+			wk.State = StateActive
+		}
+	}
+}
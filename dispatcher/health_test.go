@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRecordResultHysteresis checks the state transitions for sequences of probe results.
+func TestRecordResultHysteresis(t *testing.T) {
+	policy := DefaultHealthPolicy()
+	policy.FailureThreshold = 3
+	policy.SuccessThreshold = 2
+	withPolicy(t, &healthPolicy, policy)
+
+	tests := []struct {
+		name    string
+		start   WorkerState
+		results []bool
+		want    WorkerState
+	}{
+		{"joining activates on first success", StateJoining, []bool{true}, StateActive},
+		{"active tolerates failures below threshold", StateActive, []bool{false, false}, StateActive},
+		{"active becomes suspect at threshold", StateActive, []bool{false, false, false}, StateSuspect},
+		{"success resets the failure streak", StateActive, []bool{false, false, true, false, false}, StateActive},
+		{"suspect needs consecutive successes", StateSuspect, []bool{true}, StateSuspect},
+		{"suspect recovers at threshold", StateSuspect, []bool{true, true}, StateActive},
+		{"failure resets the success streak", StateSuspect, []bool{true, false, true}, StateSuspect},
+		{"dead worker comes back as suspect", StateDead, []bool{true}, StateSuspect},
+		{"failed probes keep a worker dead", StateDead, []bool{false, false}, StateDead},
+		{"draining ignores probes", StateDraining, []bool{true, true}, StateDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wk := &WorkerInfo{URL: "w", State: tt.start}
+			for _, ok := range tt.results {
+				wk.recordResult(ok, time.Now())
+			}
+			if wk.State != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, wk.State)
+			}
+		})
+	}
+}
+
+func TestProbesDoNotRenewLease(t *testing.T) {
+	expires := time.Now().Add(time.Second)
+	wk := &WorkerInfo{URL: "w", State: StateSuspect, LeaseExpires: expires, heartbeats: true}
+
+	wk.recordResult(true, time.Now())
+	wk.recordResult(true, time.Now())
+
+	if wk.State != StateActive {
+		t.Errorf("Expected %s, got %s", StateActive, wk.State)
+	}
+	if !wk.LeaseExpires.Equal(expires) {
+		t.Errorf("Expected the lease to stay at %v, got %v", expires, wk.LeaseExpires)
+	}
+
+	// A dead worker that sends heartbeats comes back by registering again
+	dead := &WorkerInfo{URL: "w", State: StateDead, LeaseExpires: expires, heartbeats: true}
+	dead.recordResult(true, time.Now())
+	if dead.State != StateDead || !dead.LeaseExpires.Equal(expires) {
+		t.Errorf("Expected a probe not to revive a heartbeating worker, got %s until %v", dead.State, dead.LeaseExpires)
+	}
+}
+
+func TestDeadWorkerBackoff(t *testing.T) {
+	policy := DefaultHealthPolicy()
+	policy.Interval = time.Second
+	policy.MaxBackoff = 5 * time.Second
+	withPolicy(t, &healthPolicy, policy)
+
+	now := time.Now()
+	wk := &WorkerInfo{URL: "w", State: StateDead}
+	if !wk.dueForProbe(now) {
+		t.Fatalf("Expected a newly dead worker to be probed")
+	}
+
+	for i, want := range []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		wk.recordResult(false, now)
+		if got := wk.nextProbe.Sub(now); got != want {
+			t.Errorf("Probe %d: expected a wait of %v, got %v", i+1, want, got)
+		}
+		if wk.dueForProbe(wk.nextProbe.Add(-time.Millisecond)) {
+			t.Errorf("Probe %d: expected no probe before the wait expires", i+1)
+		}
+		if !wk.dueForProbe(wk.nextProbe) {
+			t.Errorf("Probe %d: expected a probe once the wait expires", i+1)
+		}
+	}
+
+	// A good probe brings it back and restarts the backoff
+	wk.recordResult(true, now)
+	if wk.State != StateSuspect || wk.deadProbes != 0 {
+		t.Errorf("Expected suspect with no dead probes, got %s with %d", wk.State, wk.deadProbes)
+	}
+}
+
+func TestProbesRenewLeaseWithoutHeartbeats(t *testing.T) {
+	now := time.Now()
+	wk := &WorkerInfo{URL: "w", State: StateActive, LeaseExpires: now.Add(time.Second)}
+
+	// A worker registered by hand stays alive while it answers the probes
+	wk.recordResult(true, now)
+
+	if want := now.Add(leaseTTL); !wk.LeaseExpires.Equal(want) {
+		t.Errorf("Expected the lease to be renewed until %v, got %v", want, wk.LeaseExpires)
+	}
+
+	// A failed probe does not renew it
+	wk.recordResult(false, now.Add(time.Second))
+	if want := now.Add(leaseTTL); !wk.LeaseExpires.Equal(want) {
+		t.Errorf("Expected a failed probe to keep the lease at %v, got %v", want, wk.LeaseExpires)
+	}
+}
+
+func TestDeepProbe(t *testing.T) {
+	status := `{"goroutines": 50, "server": {"queue_depth": 4}}`
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, status)
+	}))
+	defer worker.Close()
+
+	tests := []struct {
+		name          string
+		maxGoroutines int
+		maxQueue      int
+		healthy       bool
+	}{
+		{"no limits", 0, 0, true},
+		{"within limits", 100, 10, true},
+		{"too many goroutines", 10, 0, false},
+		{"queue too deep", 0, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultHealthPolicy()
+			policy.DeepProbe = true
+			policy.MaxGoroutines = tt.maxGoroutines
+			policy.MaxQueueDepth = tt.maxQueue
+
+			_, err := policy.check(worker.URL)
+			if (err == nil) != tt.healthy {
+				t.Errorf("Expected healthy=%v, got error %v", tt.healthy, err)
+			}
+		})
+	}
+}
+
+func TestLoadHealthPolicy(t *testing.T) {
+	t.Setenv("HEALTH_INTERVAL", "1s")
+	t.Setenv("HEALTH_FAILURES", "5")
+	t.Setenv("HEALTH_PROBE", "status")
+
+	policy, err := LoadHealthPolicy()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if policy.Interval != time.Second || policy.FailureThreshold != 5 || !policy.DeepProbe {
+		t.Errorf("Expected values from the environment, got %+v", policy)
+	}
+	if policy.Timeout != DefaultHealthPolicy().Timeout {
+		t.Errorf("Expected default timeout, got %v", policy.Timeout)
+	}
+
+	t.Setenv("HEALTH_PROBE", "tcp")
+	if _, err := LoadHealthPolicy(); err == nil {
+		t.Errorf("Expected error for unknown probe")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Política de hedging: en las rutas elegidas, si el worker no responde antes
+// del percentil Percentile de la latencia reciente de la ruta, se envía un
+// duplicado a otro worker y se usa la primera respuesta correcta.
+type HedgePolicy struct {
+	Routes     []core.Handler // Rutas con hedging, como las capacidades ("GET /hash"); vacío = desactivado
+	Percentile float64        // Percentil de la latencia tras el que se envía el duplicado
+	MinDelay   time.Duration  // Espera mínima antes del duplicado
+	MaxDelay   time.Duration  // Espera máxima, y la usada sin muestras suficientes
+	MinSamples int            // Muestras de la ruta necesarias para usar el percentil
+}
+
+// Devuelve la política predeterminada, sin rutas con hedging.
+func DefaultHedgePolicy() HedgePolicy {
+	return HedgePolicy{
+		Percentile: 0.95,
+		MinDelay:   10 * time.Millisecond,
+		MaxDelay:   time.Second,
+		MinSamples: 20,
+	}
+}
+
+// Política activa; main la carga del entorno con LoadHedgePolicy.
+var hedgePolicy = DefaultHedgePolicy()
+
+// Carga la política desde las variables de entorno HEDGE_ROUTES (lista separada
+// por comas, como "GET /fibonacci,GET /hash"), HEDGE_PERCENTILE (entre 0 y 1),
+// HEDGE_MIN_DELAY, HEDGE_MAX_DELAY (duraciones) y HEDGE_MIN_SAMPLES (entero).
+// Las variables ausentes conservan el valor predeterminado.
+func LoadHedgePolicy() (HedgePolicy, error) {
+	policy := DefaultHedgePolicy()
+
+	if value := os.Getenv("HEDGE_ROUTES"); value != "" {
+		routes, err := parseCapabilities(strings.Split(value, ","))
+		if err != nil {
+			return policy, fmt.Errorf("bad HEDGE_ROUTES: %w", err)
+		}
+		policy.Routes = routes
+	}
+
+	// Un percentil 0 enviaría el duplicado siempre, así que se exige más que 0
+	if err := envFloat("HEDGE_PERCENTILE", &policy.Percentile, math.SmallestNonzeroFloat64, 1); err != nil {
+		return policy, err
+	}
+
+	durations := map[string]*time.Duration{
+		"HEDGE_MIN_DELAY": &policy.MinDelay,
+		"HEDGE_MAX_DELAY": &policy.MaxDelay,
+	}
+	for name, target := range durations {
+		if err := envDuration(name, target); err != nil {
+			return policy, err
+		}
+	}
+
+	if err := envInt("HEDGE_MIN_SAMPLES", &policy.MinSamples, 1); err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+// Devuelve la ruta con hedging que corresponde a la petición ("GET /hash"),
+// o "" si la petición no se duplica.
+func (policy HedgePolicy) route(method, path string) string {
+	handler, _, _ := core.FindHandler(policy.Routes, method, path)
+	if handler == nil {
+		return ""
+	}
+	return handler.Method + " " + handler.Path
+}
+
+// Espera antes de duplicar una petición de la ruta: el percentil de su
+// latencia reciente entre MinDelay y MaxDelay, o MaxDelay sin muestras suficientes.
+func (policy HedgePolicy) delay(route string) time.Duration {
+	latency, samples := routeLatency(route).percentile(policy.Percentile)
+	if samples < policy.MinSamples {
+		return policy.MaxDelay
+	}
+	return min(max(latency, policy.MinDelay), policy.MaxDelay)
+}
+
+// Latencias de las últimas respuestas correctas de una ruta.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// Muestras que se guardan por ruta.
+const latencySamples = 200
+
+func (l *latencyWindow) add(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, latency)
+		return
+	}
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % latencySamples
+}
+
+// Devuelve el percentil p de las muestras y cuántas hay.
+func (l *latencyWindow) percentile(p float64) (time.Duration, int) {
+	l.mu.Lock()
+	sorted := append([]time.Duration(nil), l.samples...)
+	l.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)], len(sorted)
+}
+
+var (
+	latenciesMu sync.Mutex
+	latencies   = make(map[string]*latencyWindow)
+
+	hedgesSent atomic.Int64 // Duplicados enviados
+	hedgesWon  atomic.Int64 // Duplicados que respondieron antes que el original
+)
+
+// Devuelve la ventana de latencias de la ruta, creándola si no existe.
+func routeLatency(route string) *latencyWindow {
+	latenciesMu.Lock()
+	defer latenciesMu.Unlock()
+
+	window, ok := latencies[route]
+	if !ok {
+		window = &latencyWindow{}
+		latencies[route] = window
+	}
+	return window
+}
+
+// DoHedgedRequest envía una petición idempotente de una ruta con hedging a un
+// worker y, si no responde antes de hedgePolicy.delay, un duplicado a otro.
+// Devuelve la primera respuesta correcta y cancela el otro intento. Si el
+// primer intento falla antes de la espera, el duplicado sale enseguida y hace
+// de reintento. Cada duplicado gasta presupuesto de reintentos. No se duplica
+// una petición cuyo ctx ya terminó.
+func DoHedgedRequest(ctx context.Context, route, method, url string, payload []byte, headers http.Header) (*http.Response, error) {
+	key := requestKey(url, headers)
+	path, _, _ := strings.Cut(url, "?")
+	tried := make(map[string]bool)
+	results := make(chan attemptResult, 2)
+	cancels := make(map[*WorkerInfo]context.CancelFunc)
+	retryBudget.deposit()
+
+	// Envía la petición a un worker aún no intentado; nil si no queda ninguno
+	launch := func() *WorkerInfo {
+		for {
+			wk := PickWorker(method, path, key, tried)
+			if wk == nil {
+				return nil
+			}
+			tried[wk.URL] = true
+			ticket, allowed := wk.breaker.allow(time.Now())
+			if !allowed {
+				continue
+			}
+
+			attemptCtx, cancel := context.WithCancel(ctx)
+			cancels[wk] = cancel
+			go func() { results <- send(attemptCtx, cancel, wk, ticket, method, url, payload, headers) }()
+			return wk
+		}
+	}
+
+	original := launch()
+	if original == nil {
+		return nil, errors.New("all workers failed: no worker available")
+	}
+	pending := 1
+	hedged := false
+
+	hedge := func() {
+		hedged = true
+		if ctx.Err() != nil || !retryBudget.withdraw() {
+			return
+		}
+		if launch() == nil {
+			retryBudget.refund()
+			return
+		}
+		pending++
+		hedgesSent.Add(1)
+	}
+
+	timer := time.NewTimer(hedgePolicy.delay(route))
+	defer timer.Stop()
+
+	var last attemptResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !hedged {
+				hedge()
+			}
+		case result := <-results:
+			pending--
+			if result.ok() {
+				routeLatency(route).add(result.latency)
+				if last.resp != nil {
+					last.resp.Body.Close()
+				}
+				if result.wk != original {
+					hedgesWon.Add(1)
+				}
+				// se cancela el otro intento y se descarta su respuesta si llega
+				for wk, cancel := range cancels {
+					if wk != result.wk {
+						cancel()
+					}
+				}
+				go discardResults(results, pending)
+				return result.resp, nil
+			}
+
+			if last.resp != nil {
+				last.resp.Body.Close()
+			}
+			last = result
+			if !hedged {
+				hedge()
+			}
+		}
+	}
+
+	if last.resp != nil {
+		// el worker respondió: el cliente recibe su respuesta tal cual
+		return last.resp, nil
+	}
+	return nil, fmt.Errorf("all workers failed: %w", last.err)
+}
+
+// Cierra las respuestas de los n intentos que aún no terminaron.
+func discardResults(results <-chan attemptResult, n int) {
+	for ; n > 0; n-- {
+		if result := <-results; result.resp != nil {
+			result.resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withHedgePolicy hedges GET /hash after at most 20ms and resets the latency windows.
+func withHedgePolicy(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		latenciesMu.Lock()
+		latencies = make(map[string]*latencyWindow)
+		latenciesMu.Unlock()
+	})
+
+	routes, _ := parseCapabilities([]string{"GET /hash"})
+	policy := DefaultHedgePolicy()
+	policy.Routes = routes
+	policy.MaxDelay = 20 * time.Millisecond
+	withPolicy(t, &hedgePolicy, policy)
+}
+
+func TestHedgePolicyRoute(t *testing.T) {
+	withHedgePolicy(t)
+
+	if got := hedgePolicy.route("GET", "/hash"); got != "GET /hash" {
+		t.Errorf("Expected GET /hash, got %q", got)
+	}
+	if got := hedgePolicy.route("GET", "/fibonacci"); got != "" {
+		t.Errorf("Expected no hedging for /fibonacci, got %q", got)
+	}
+}
+
+func TestHedgeDelayUsesPercentile(t *testing.T) {
+	withHedgePolicy(t)
+	hedgePolicy.MinSamples = 10
+	hedgePolicy.MaxDelay = time.Second
+
+	window := routeLatency("GET /hash")
+	for i := 1; i <= 9; i++ {
+		window.add(time.Duration(i) * time.Millisecond)
+	}
+	if got := hedgePolicy.delay("GET /hash"); got != time.Second {
+		t.Errorf("Expected MaxDelay without enough samples, got %v", got)
+	}
+
+	for i := 10; i <= 100; i++ {
+		window.add(time.Duration(i) * time.Millisecond)
+	}
+	if got := hedgePolicy.delay("GET /hash"); got != 95*time.Millisecond {
+		t.Errorf("Expected p95 of 95ms, got %v", got)
+	}
+}
+
+func TestHedgedRequestUsesFastestWorker(t *testing.T) {
+	withHedgePolicy(t)
+	withRetryPolicy(t)
+
+	cancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(2 * time.Second):
+			w.Write([]byte("slow"))
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+
+	resetWorkers(&WorkerInfo{URL: slow.URL, State: StateActive}, &WorkerInfo{URL: fast.URL, State: StateActive})
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+	won := hedgesWon.Load()
+
+	start := time.Now()
+	resp, err := DoHedgedRequest(context.Background(), "GET /hash", "GET", "/hash?text=a", nil, http.Header{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "fast" || time.Since(start) > time.Second {
+		t.Errorf("Expected the fast worker to answer quickly, got %q after %v", body, time.Since(start))
+	}
+	if hedgesWon.Load() != won+1 {
+		t.Errorf("Expected the hedge to win")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the slow request to be cancelled")
+	}
+}
+
+func TestHedgedRequestRetriesFailureImmediately(t *testing.T) {
+	withHedgePolicy(t)
+	withRetryPolicy(t)
+	hedgePolicy.MaxDelay = time.Minute
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer healthy.Close()
+
+	resetWorkers(&WorkerInfo{URL: failing.URL, State: StateActive}, &WorkerInfo{URL: healthy.URL, State: StateActive})
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, _ := DoHedgedRequest(context.Background(), "GET /hash", "GET", "/hash", nil, http.Header{})
+		done <- resp
+	}()
+
+	select {
+	case resp := <-done:
+		if resp == nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 from the healthy worker, got %v", resp)
+		}
+		resp.Body.Close()
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the failure to be hedged without waiting for the delay")
+	}
+}
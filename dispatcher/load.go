@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Carga que reporta el worker en /status; se actualiza en cada sondeo.
+type WorkerLoad struct {
+	InFlight   int64     `json:"in_flight"`   // Solicitudes que el worker está atendiendo
+	QueueDepth int       `json:"queue_depth"` // Conexiones esperando en su cola
+	Goroutines int       `json:"goroutines"`  // Goroutines del proceso
+	CPULoad    float64   `json:"cpu_load"`    // Fracción de CPU que usa el proceso del worker (1 = todas)
+	Reported   time.Time `json:"reported"`    // Momento del reporte (cero si nunca reportó)
+}
+
+// Respuesta de /status del worker, con los campos que usa el dispatcher.
+type workerStatus struct {
+	Goroutines int     `json:"goroutines"`
+	CPULoad    float64 `json:"cpu_load"`
+	Server     struct {
+		InFlight   int64 `json:"in_flight"`
+		QueueDepth int   `json:"queue_depth"`
+	} `json:"server"`
+}
+
+// Convierte el estado reportado en la carga del worker. Las solicitudes en
+// curso no cuentan el propio sondeo a /status, que el worker atiende mientras
+// responde.
+func (status workerStatus) load(now time.Time) WorkerLoad {
+	return WorkerLoad{
+		InFlight:   max(status.Server.InFlight-1, 0),
+		QueueDepth: status.Server.QueueDepth,
+		Goroutines: status.Goroutines,
+		CPULoad:    status.CPULoad,
+		Reported:   now,
+	}
+}
+
+// Guarda la última carga reportada por el worker.
+func (wk *WorkerInfo) setLoad(load WorkerLoad) {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+	wk.Load = load
+}
+
+// Puntuación de carga del worker (menor = más libre), relativa a su peso.
+// Las peticiones en curso son las del dispatcher o las que reporta el worker,
+// la mayor de las dos: el reporte incluye las de otros clientes pero llega con
+// el retraso del sondeo, y el contador propio es exacto al instante. Se suman
+// las conexiones en cola y la carga de CPU, que desempata entre workers igual
+// de ocupados.
+func (wk *WorkerInfo) loadScore() float64 {
+	wk.mu.Lock()
+	load := wk.Load
+	weight := max(wk.Weight, 1)
+	wk.mu.Unlock()
+
+	score := float64(max(wk.outstanding.Load(), load.InFlight))
+	score += float64(load.QueueDepth) + load.CPULoad
+
+	return score / float64(weight)
+}
+
+// --- Menos cargado ---
+
+// Elige el worker con menor puntuación de carga. A igualdad de carga reparte
+// en orden circular, para no cargar siempre el primero mientras todos estén libres.
+type leastLoaded struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (s *leastLoaded) Name() string { return "least-loaded" }
+
+func (s *leastLoaded) Pick(candidates []*WorkerInfo, _ string) *WorkerInfo {
+	s.mu.Lock()
+	start := s.next
+	s.next++
+	s.mu.Unlock()
+
+	var best *WorkerInfo
+	var bestScore float64
+	for i := range candidates {
+		wk := candidates[(start+i)%len(candidates)]
+		if score := wk.loadScore(); best == nil || score < bestScore {
+			best, bestScore = wk, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeRecordsReportedLoad(t *testing.T) {
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"goroutines": 12, "cpu_load": 0.5, "server": {"in_flight": 3, "queue_depth": 2}}`)
+	}))
+	defer worker.Close()
+
+	wk := &WorkerInfo{URL: worker.URL, State: StateJoining}
+	probeWorker(wk)
+
+	if wk.State != StateActive {
+		t.Errorf("Expected %s, got %s", StateActive, wk.State)
+	}
+	// The reported in_flight includes the probe itself
+	load := wk.Load
+	if load.InFlight != 2 || load.QueueDepth != 2 || load.Goroutines != 12 || load.CPULoad != 0.5 {
+		t.Errorf("Unexpected load %+v", load)
+	}
+	if load.Reported.IsZero() {
+		t.Errorf("Expected report time to be set")
+	}
+}
+
+func TestProbeFallsBackToPing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {})
+	worker := httptest.NewServer(mux)
+	defer worker.Close()
+
+	wk := &WorkerInfo{URL: worker.URL, State: StateJoining}
+	probeWorker(wk)
+
+	if wk.State != StateActive {
+		t.Errorf("Expected %s, got %s", StateActive, wk.State)
+	}
+	if !wk.Load.Reported.IsZero() {
+		t.Errorf("Expected no load report, got %+v", wk.Load)
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	pool := newTestWorkers(3)
+	// w0: 4 requests from this dispatcher; w1: reports 6 in flight;
+	// w2: 2 in flight plus a queue and a busy CPU.
+	pool[0].outstanding.Store(4)
+	pool[1].Load = WorkerLoad{InFlight: 6}
+	pool[2].Load = WorkerLoad{InFlight: 2, QueueDepth: 1, CPULoad: 0.5}
+
+	s := &leastLoaded{}
+	for i := 0; i < 3; i++ {
+		if got := s.Pick(pool, ""); got != pool[2] {
+			t.Errorf("Expected %s, got %s", pool[2].URL, got.URL)
+		}
+	}
+
+	// A heavier weight halves the score: w1 (6/2 = 3) beats w2 (3.5).
+	pool[1].Weight = 2
+	if got := s.Pick(pool, ""); got != pool[1] {
+		t.Errorf("Expected %s, got %s", pool[1].URL, got.URL)
+	}
+}
+
+func TestLeastLoadedRotatesTies(t *testing.T) {
+	pool := newTestWorkers(3)
+
+	counts := countPicks(&leastLoaded{}, pool, 300, noKey)
+	for _, wk := range pool {
+		if counts[wk.URL] != 100 {
+			t.Errorf("Expected 100 picks for %s, got %d", wk.URL, counts[wk.URL])
+		}
+	}
+}
@@ -2,68 +2,167 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
 )
 
 type WorkerInfo struct {
-    URL       string
-    Active    bool
-    TasksDone int
-    mu        sync.Mutex
+    URL          string
+    State        WorkerState // Estado en el pool (ver membership.go)
+    TasksDone    int
+    Capabilities []string  // Rutas que anuncia el worker ("GET /ping", ...)
+    Weight       int       // Peso para el round-robin ponderado (0 = 1)
+    LastSeen     time.Time // Último registro, latido o /ping correcto
+    LeaseExpires time.Time // Si no se renueva antes, el worker pasa a muerto
+    mu           sync.Mutex
+
+    failures   int       // Sondeos fallidos seguidos
+    successes  int       // Sondeos correctos seguidos
+    heartbeats bool      // Envió latidos desde que se registró; si no, los sondeos correctos renuevan su lease
+    deadProbes int       // Sondeos desde que el worker murió que no lo recuperaron
+    nextProbe  time.Time // Próximo sondeo de un worker muerto
+
+    routes      []core.Handler // Capacidades como rutas (nil = atiende todo, ver capabilities.go)
+    outstanding atomic.Int64   // Peticiones enviadas al worker aún sin terminar
+    Load        WorkerLoad   // Última carga reportada por el worker (ver load.go)
+    breaker     breaker      // Circuit breaker de las peticiones al worker (ver breaker.go)
 }
 
 var (
     workers []*WorkerInfo
-    rrIndex int
     mu      sync.Mutex
 )
 
 // --- Registro dinámico de workers ---
 
 // RegisterHandler añade un nuevo worker al dispatcher, sin duplicados.
+// El cuerpo es {"url": ..., "capabilities": ["GET /ping", ...]}; las capacidades son
+// opcionales y, sin ellas, el worker recibe cualquier ruta.
+// El worker entra como "joining" con un lease de leaseTTL (cabecera X-Lease-Ttl)
+// y pasa a "active" en cuanto responde a /ping.
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-    var payload struct{ URL string }
+    var payload struct {
+        URL          string
+        Capabilities []string
+        Weight       int
+    }
     if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
         http.Error(w, "Bad JSON", http.StatusBadRequest)
         return
     }
+    routes, err := parseCapabilities(payload.Capabilities)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if err := verifyWorkerIdentity(r, payload.URL); err != nil {
+        http.Error(w, err.Error(), http.StatusForbidden)
+        return
+    }
     mu.Lock()
     defer mu.Unlock()
-    // Si ya existe, simplemente lo reactivamos si estaba inactivo
-    for _, wk := range workers {
-        if wk.URL == payload.URL {
-            wk.mu.Lock()
-            wk.Active = true
-            wk.mu.Unlock()
-            w.WriteHeader(http.StatusNoContent)
-            return
+    // Si ya existe, vuelve a unirse con sus capacidades actualizadas
+    var wk *WorkerInfo
+    for _, existing := range workers {
+        if existing.URL == payload.URL {
+            wk = existing
+            break
         }
     }
     // Si no existe, lo añadimos al slice
-    workers = append(workers, &WorkerInfo{URL: payload.URL, Active: true})
+    if wk == nil {
+        wk = &WorkerInfo{URL: payload.URL}
+        workers = append(workers, wk)
+    }
+
+    wk.mu.Lock()
+    wk.State = StateJoining
+    wk.Capabilities = payload.Capabilities
+    wk.routes = routes
+    wk.Weight = payload.Weight
+    wk.heartbeats = false
+    wk.deadProbes = 0
+    wk.renewLease(time.Now())
+    wk.mu.Unlock()
+
+    // Comprueba enseguida que el dispatcher llega al worker
+    go probeWorker(wk)
+
+    setLeaseHeader(w)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// HeartbeatHandler renueva el lease del worker {"url": ...}.
+// Responde 404 si el worker no está registrado (o ya fue dado por muerto),
+// para que vuelva a registrarse.
+func HeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+    var payload struct{ URL string }
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
+        http.Error(w, "Bad JSON", http.StatusBadRequest)
+        return
+    }
+    if err := verifyWorkerIdentity(r, payload.URL); err != nil {
+        http.Error(w, err.Error(), http.StatusForbidden)
+        return
+    }
+    renewed := false
+    mu.Lock()
+    for _, wk := range workers {
+        if wk.URL != payload.URL {
+            continue
+        }
+        wk.mu.Lock()
+        if wk.State != StateDead && wk.State != StateDraining {
+            wk.renewLease(time.Now())
+            wk.heartbeats = true
+            renewed = true
+        }
+        wk.mu.Unlock()
+        break
+    }
+    mu.Unlock()
+
+    // La respuesta se escribe sin tener los locks
+    if !renewed {
+        http.Error(w, "Unknown worker", http.StatusNotFound)
+        return
+    }
+    setLeaseHeader(w)
     w.WriteHeader(http.StatusNoContent)
 }
 
-// UnregisterHandler elimina un worker cuando apaga
+// UnregisterHandler da de baja un worker cuando apaga: deja de recibir trabajo
+// ("draining") y se elimina en la siguiente revisión de leases.
 func UnregisterHandler(w http.ResponseWriter, r *http.Request) {
     var payload struct{ URL string }
     if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
         http.Error(w, "Bad JSON", http.StatusBadRequest)
         return
     }
+    if err := verifyWorkerIdentity(r, payload.URL); err != nil {
+        http.Error(w, err.Error(), http.StatusForbidden)
+        return
+    }
     mu.Lock()
     defer mu.Unlock()
-    for i, wk := range workers {
+    for _, wk := range workers {
         if wk.URL == payload.URL {
-            workers = append(workers[:i], workers[i+1:]...)
+            wk.mu.Lock()
+            wk.State = StateDraining
+            wk.mu.Unlock()
             break
         }
     }
@@ -74,42 +173,38 @@ func UnregisterHandler(w http.ResponseWriter, r *http.Request) {
 
 func HealthChecker() {
     for {
+        // Expira los leases vencidos y elimina los workers muertos o drenados
+        sweepWorkers(time.Now())
+
         mu.Lock()
         pool := make([]*WorkerInfo, len(workers))
         copy(pool, workers)
         mu.Unlock()
 
         var wg sync.WaitGroup
+        now := time.Now()
         for _, wk := range pool {
+            // Los workers drenándose no se consultan y los muertos, con espera creciente
+            if !wk.dueForProbe(now) {
+                continue
+            }
+
             wg.Add(1)
             go func(wk *WorkerInfo) {
                 defer wg.Done()
-                client := http.Client{Timeout: 2 * time.Second}
-                resp, err := client.Get(wk.URL + "/ping")
-                wk.mu.Lock()
-                wk.Active = (err == nil && resp.StatusCode == http.StatusOK)
-                wk.mu.Unlock()
+                probeWorker(wk)
             }(wk)
         }
         wg.Wait()
-        time.Sleep(5 * time.Second)
+        time.Sleep(healthPolicy.Interval)
     }
 }
 
 // --- Utilidades de workers ---
 
-// GetNextWorker devuelve el siguiente activo en round-robin
+// GetNextWorker devuelve el siguiente worker activo según la estrategia de balanceo
 func GetNextWorker() *WorkerInfo {
-    mu.Lock()
-    defer mu.Unlock()
-    n := len(workers)
-    for i := 0; i < n; i++ {
-        rrIndex = (rrIndex + 1) % n
-        if workers[rrIndex].Active {
-            return workers[rrIndex]
-        }
-    }
-    return nil
+    return PickWorker("", "", "", nil)
 }
 
 // GetActiveWorkers devuelve la lista de workers actualmente activos
@@ -118,59 +213,155 @@ func GetActiveWorkers() []*WorkerInfo {
     defer mu.Unlock()
     active := make([]*WorkerInfo, 0, len(workers))
     for _, wk := range workers {
-        wk.mu.Lock()
-        if wk.Active {
+        if wk.IsActive() {
             active = append(active, wk)
         }
-        wk.mu.Unlock()
     }
     return active
 }
 
-// DoRequestWithRetry intenta hasta maxTries repartir la petición si un worker falla
-func DoRequestWithRetry(method, url string, payload []byte, headers http.Header, maxTries int) (*http.Response, error) {
+// Resultado de enviar una petición a un worker.
+type attemptResult struct {
+    wk      *WorkerInfo
+    resp    *http.Response // Respuesta del worker (nil si err)
+    err     error
+    latency time.Duration // Tiempo hasta recibir las cabeceras
+}
+
+// Indica si el worker respondió sin error de servidor.
+func (r attemptResult) ok() bool {
+    return r.err == nil && r.resp.StatusCode < 500
+}
+
+// Envía la petición a wk, que ya pasó breaker.allow con ticket, con el plazo de ctx en la
+// cabecera X-Request-Deadline. Mide el resultado en el circuit breaker (salvo si
+// ctx terminó antes) y cuenta la petición como en curso hasta que se cierra el
+// cuerpo de la respuesta, que también cancela ctx.
+func send(ctx context.Context, cancel context.CancelFunc, wk *WorkerInfo, ticket breakerTicket, method, url string, payload []byte, headers http.Header) attemptResult {
+    req, err := http.NewRequestWithContext(ctx, method, wk.URL+url, bytes.NewReader(payload))
+    if err != nil {
+        cancel()
+        wk.breaker.release(ticket)
+        return attemptResult{wk: wk, err: err}
+    }
+    req.Header = headers.Clone()
+    req.Header.Del("Transfer-Encoding")
+    req.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+    if deadline, ok := ctx.Deadline(); ok {
+        req.Header.Set(core.DeadlineHeader, core.FormatDeadline(deadline))
+    }
+
+    wk.outstanding.Add(1)
+    start := time.Now()
+    resp, err := httpClient.Do(req)
+    result := attemptResult{wk: wk, resp: resp, err: err, latency: time.Since(start)}
+
+    if ctx.Err() != nil {
+        // cancelada por el dispatcher o el cliente, o vencido su plazo: no dice nada del worker
+        wk.breaker.release(ticket)
+    } else {
+        wk.breaker.record(ticket, result.ok(), result.latency, time.Now())
+    }
+
+    if err != nil {
+        cancel()
+        wk.outstanding.Add(-1)
+        return result
+    }
+
+    // la petición sigue en curso hasta que se termina de leer la respuesta
+    resp.Body = &outstandingBody{ReadCloser: resp.Body, wk: wk, cancel: cancel}
+    if result.ok() {
+        wk.mu.Lock()
+        wk.TasksDone++
+        wk.mu.Unlock()
+    }
+    return result
+}
+
+// DoRequestWithRetry envía la petición a un worker y, si falla, la repite en
+// otro según retryPolicy: solo si su método o su ruta lo permiten, si lleva
+// Idempotency-Key o si no llegó a enviarse, con espera exponencial entre intentos y mientras quede
+// presupuesto de reintentos. Solo se eligen workers que atienden el método y la
+// ruta de la petición. Si el último intento fue un 5xx, se devuelve esa respuesta.
+// No se reintenta una vez que ctx termina (el cliente se fue o venció el plazo).
+func DoRequestWithRetry(ctx context.Context, method, url string, payload []byte, headers http.Header) (*http.Response, error) {
     var lastErr error
+    var lastResp *http.Response // último 5xx, abierto hasta saber si se reintenta
     tried := make(map[string]bool)
-
-    for attempt := 0; attempt < maxTries; attempt++ {
-        wk := GetNextWorker()
+    key := requestKey(url, headers)
+    path, _, _ := strings.Cut(url, "?")
+    retryable := retryPolicy.retryable(method, path, headers)
+    retryBudget.deposit()
+
+    for attempt := 1; attempt <= retryPolicy.MaxAttempts; attempt++ {
+        // la estrategia elige entre los workers activos capaces que aún no se intentaron
+        wk := PickWorker(method, path, key, tried)
         if wk == nil {
             break
         }
-        // evita reintentar el mismo worker inmediatamente
-        if tried[wk.URL] {
-            continue
-        }
         tried[wk.URL] = true
 
-        req, err := http.NewRequest(method, wk.URL+url, bytes.NewReader(payload))
-        if err != nil {
-            lastErr = err
+        // cada reintento gasta presupuesto
+        if attempt > 1 && !retryBudget.withdraw() {
+            lastErr = fmt.Errorf("retry budget exhausted: %w", lastErr)
+            break
+        }
+
+        // el circuit breaker pudo abrirse, o agotar sus pruebas, tras elegir el worker
+        ticket, allowed := wk.breaker.allow(time.Now())
+        if !allowed {
+            if attempt > 1 {
+                retryBudget.refund()
+            }
+            lastErr = errors.New("circuit open for " + wk.URL)
+            attempt--
             continue
         }
-        req.Header = headers.Clone()
-        req.Header.Del("Transfer-Encoding")
-        req.Header.Set("Content-Length", strconv.Itoa(len(payload)))
 
-        resp, err := (&http.Client{}).Do(req)
-        if err == nil && resp.StatusCode < 500 {
-            wk.mu.Lock()
-            wk.TasksDone++
-            wk.mu.Unlock()
-            return resp, nil
+        if attempt > 1 {
+            // se reintenta: se descarta el fallo anterior y se espera
+            if lastResp != nil {
+                lastResp.Body.Close()
+                lastResp = nil
+            }
+            if err := core.Sleep(ctx, retryPolicy.backoff(attempt-1)); err != nil {
+                wk.breaker.release(ticket)
+                retryBudget.refund()
+                lastErr = err
+                break
+            }
         }
-        // marcar inactivo y guardar error
-        wk.mu.Lock()
-        wk.Active = false
-        wk.mu.Unlock()
-        if err != nil {
-            lastErr = err
+
+        attemptCtx, cancel := context.WithCancel(ctx)
+        result := send(attemptCtx, cancel, wk, ticket, method, url, payload, headers)
+        if result.ok() {
+            return result.resp, nil
+        }
+
+        // el fallo cuenta para el circuit breaker del worker; guardar error
+        if result.err != nil {
+            lastErr = result.err
         } else {
-            lastErr = errors.New("status " + resp.Status)
-            resp.Body.Close()
+            lastErr = errors.New("status " + result.resp.Status)
+            lastResp = result.resp
+        }
+
+        // una petición que llegó al worker solo se repite si es idempotente,
+        // y ninguna se repite si el cliente ya no espera la respuesta
+        if (!retryable && !notSent(result.err)) || ctx.Err() != nil {
+            break
         }
     }
-    return nil, fmt.Errorf("all workers failed: %v", lastErr)
+
+    if lastResp != nil {
+        // el worker respondió: el cliente recibe su respuesta tal cual
+        return lastResp, nil
+    }
+    if lastErr == nil {
+        lastErr = errors.New("no worker available")
+    }
+    return nil, fmt.Errorf("all workers failed: %w", lastErr)
 }
 
 // ProxyHandler reenvía cualquier ruta GENÉRICA a un worker con retry
@@ -182,9 +373,38 @@ func ProxyHandler(w http.ResponseWriter, r *http.Request) {
     }
     defer r.Body.Close()
 
-    resp, err := DoRequestWithRetry(r.Method, r.RequestURI, payload, r.Header, len(workers))
+    // La petición se abandona si el cliente se va o vence su plazo (ver deadline.go)
+    ctx, cancel, err := requestContext(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    defer cancel()
+
+    // Solo se reenvía si algún worker activo atiende el método y la ruta
+    switch status, allowed := routeStatus(r.Method, r.URL.Path); status {
+    case 0:
+    case http.StatusMethodNotAllowed:
+        w.Header().Set("Allow", strings.Join(allowed, ", "))
+        http.Error(w, "Method not allowed", status)
+        return
+    case http.StatusNotFound:
+        http.Error(w, "No worker serves "+r.Method+" "+r.URL.Path, status)
+        return
+    default:
+        http.Error(w, "No active workers", status)
+        return
+    }
+
+    // Las rutas con hedging duplican las peticiones idempotentes lentas
+    var resp *http.Response
+    if route := hedgePolicy.route(r.Method, r.URL.Path); route != "" && retryPolicy.retryable(r.Method, r.URL.Path, r.Header) {
+        resp, err = DoHedgedRequest(ctx, route, r.Method, r.RequestURI, payload, r.Header)
+    } else {
+        resp, err = DoRequestWithRetry(ctx, r.Method, r.RequestURI, payload, r.Header)
+    }
     if err != nil {
-        http.Error(w, err.Error(), http.StatusBadGateway)
+        writeProxyError(w, err)
         return
     }
     defer resp.Body.Close()
@@ -202,13 +422,22 @@ func ProxyHandler(w http.ResponseWriter, r *http.Request) {
 func StatusHandler(w http.ResponseWriter, _ *http.Request) {
     mu.Lock()
     defer mu.Unlock()
+    now := time.Now()
     out := make([]map[string]interface{}, 0, len(workers))
     for _, wk := range workers {
         wk.mu.Lock()
         out = append(out, map[string]interface{}{
-            "url":        wk.URL,
-            "active":     wk.Active,
-            "tasks_done": wk.TasksDone,
+            "url":           wk.URL,
+            "active":        wk.State == StateActive,
+            "state":         wk.State,
+            "tasks_done":    wk.TasksDone,
+            "capabilities":  wk.Capabilities,
+            "weight":        max(wk.Weight, 1),
+            "outstanding":   wk.outstanding.Load(),
+            "load":          wk.Load,
+            "breaker":       wk.breaker.stats(now),
+            "last_seen":     wk.LastSeen,
+            "lease_expires": wk.LeaseExpires,
         })
         wk.mu.Unlock()
     }
@@ -253,11 +482,26 @@ func MatrixHandler(w http.ResponseWriter, r *http.Request) {
     }
     defer r.Body.Close()
 
-    // 2) Split de A en bloques de filas
-    rowBlocks := SplitMatrixRows(payload.A, len(GetActiveWorkers()))
+    // Los bloques se abandonan si el cliente se va o vence su plazo
+    ctx, cancel, err := requestContext(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    defer cancel()
 
-    // 3) Preparar slice donde guardaremos cada respuesta
+    // 2) Split de A en bloques de filas, uno por worker que atiende /matrix/part
+    //    y puede recibir peticiones (con el circuit breaker cerrado o de prueba)
+    serving := len(ServingWorkers("POST", "/matrix/part"))
+    if serving == 0 {
+        http.Error(w, "No worker serves POST /matrix/part", http.StatusServiceUnavailable)
+        return
+    }
+    rowBlocks := SplitMatrixRows(payload.A, serving)
+
+    // 3) Preparar slices donde guardaremos cada respuesta o su error
     responses := make([][][]float64, len(rowBlocks))
+    failures := make([]error, len(rowBlocks))
 
     // 4) Lanzar un goroutine por bloque
     var wg sync.WaitGroup
@@ -272,30 +516,46 @@ func MatrixHandler(w http.ResponseWriter, r *http.Request) {
                 "b": payload.B,
             })
 
-            // hacer POST con retry
+            // hacer POST con retry: multiplicar un bloque no tiene efectos,
+            // así que retryPolicy lo repite en otro worker (ver DefaultRetryPolicy)
             resp, err := DoRequestWithRetry(
+                ctx,
                 "POST",
                 "/matrix/part",
                 subPayload,
                 http.Header{"Content-Type": []string{"application/json"}},
-                len(workers),
             )
             if err != nil {
-                log.Printf("block %d failed: %v", idx, err)
+                failures[idx] = fmt.Errorf("block %d failed: %w", idx, err)
                 return
             }
             defer resp.Body.Close()
+            if resp.StatusCode != http.StatusOK {
+                failures[idx] = fmt.Errorf("block %d failed: status %s", idx, resp.Status)
+                return
+            }
 
             // decodificar respuesta del worker
             var partRes [][]float64
             if err := json.NewDecoder(resp.Body).Decode(&partRes); err != nil {
-                log.Printf("decode block %d failed: %v", idx, err)
+                failures[idx] = fmt.Errorf("decode block %d failed: %w", idx, err)
                 return
             }
             responses[idx] = partRes
         }(i, blk)
     }
     wg.Wait()
+    if err := ctx.Err(); err != nil {
+        writeProxyError(w, err)
+        return
+    }
+
+    // Sin todos los bloques el resultado estaría incompleto
+    if err := errors.Join(failures...); err != nil {
+        log.Printf("matrix failed: %v", err)
+        writeProxyError(w, err)
+        return
+    }
 
     // 5) Stitch de las sub-matrices y respuesta final
     result := StitchMatrix(responses)
@@ -311,17 +571,70 @@ func main() {
     //     "http://worker3:8080",
     // }
     // for _, u := range initWorkers {
-    //     workers = append(workers, &WorkerInfo{URL: u, Active: true})
+    //     workers = append(workers, &WorkerInfo{URL: u, State: StateActive})
     // }
 
+    policy, err := LoadHealthPolicy()
+    if err != nil {
+        log.Fatal(err)
+    }
+    healthPolicy = policy
+    healthClient.Timeout = policy.Timeout
+
+    retryPolicy, err = LoadRetryPolicy()
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    breakerPolicy, err = LoadBreakerPolicy()
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    hedgePolicy, err = LoadHedgePolicy()
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    requestTimeout, err = LoadRequestTimeout()
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    // Las rutas /admin exigen este token o un certificado de cliente (ver requireAdmin)
+    adminToken = os.Getenv("ADMIN_TOKEN")
+
+    // Estrategia de balanceo inicial; se cambia en caliente con /admin/strategy
+    if name := os.Getenv("LB_STRATEGY"); name != "" {
+        s, err := NewStrategy(name)
+        if err != nil {
+            log.Fatal(err)
+        }
+        SetStrategy(s)
+    }
+
     go HealthChecker()
 
     http.HandleFunc("/register", RegisterHandler)
     http.HandleFunc("/unregister", UnregisterHandler)
+    http.HandleFunc("/heartbeat", HeartbeatHandler)
+    http.HandleFunc("/admin/strategy", requireAdmin(StrategyHandler))
     http.HandleFunc("/workers", StatusHandler)
+    http.HandleFunc("/metrics", MetricsHandler)
     http.HandleFunc("/matrix", MatrixHandler)    // endpoint completo
     http.HandleFunc("/", ProxyHandler)           // proxy para todo lo demás
 
+    // mTLS opcional entre dispatcher y workers (ver setupTLS)
+    tlsConfig, err := setupTLS()
+    if err != nil {
+        log.Fatal(err)
+    }
+    if tlsConfig != nil {
+        server := &http.Server{Addr: ":8000", TLSConfig: tlsConfig}
+        log.Println("Dispatcher escuchando en :8000 (mTLS)")
+        log.Fatal(server.ListenAndServeTLS("", ""))
+    }
+
     log.Println("Dispatcher escuchando en :8000")
     log.Fatal(http.ListenAndServe(":8000", nil))
 }
@@ -8,7 +8,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -97,8 +100,8 @@ func TestMatrixMultiplicationIntegration(t *testing.T) {
 	// Reset dispatcher's global state
 	mu.Lock()
 	workers = nil
-	rrIndex = 0
 	mu.Unlock()
+	SetStrategy(&roundRobin{})
 
 	// Start the Dispatcher Server
 	dispatcherMux := http.NewServeMux()
@@ -245,3 +248,43 @@ func TestMatrixMultiplicationIntegration(t *testing.T) {
 		t.Logf("Matrix multiplication successful. Result matches expected output.")
 	}
 }
+
+// TestMatrixHandlerFailsOnFailedBlock checks that the matrix is split only among
+// workers whose breaker admits requests and that a failed block is an error,
+// not a 200 with missing rows.
+func TestMatrixHandlerFailsOnFailedBlock(t *testing.T) {
+	withRetryPolicy(t)
+	withBreakerPolicy(t)
+
+	var hits atomic.Int64
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	// The open-breaker worker would get a block if it were counted
+	open := &WorkerInfo{URL: "http://open:8080", State: StateActive}
+	open.breaker.state = BreakerOpen
+	open.breaker.openedAt = time.Now()
+	resetWorkers(&WorkerInfo{URL: failing.URL, State: StateActive}, open)
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	if serving := ServingWorkers("POST", "/matrix/part"); len(serving) != 1 {
+		t.Errorf("Expected 1 serving worker, got %d", len(serving))
+	}
+
+	body := `{"a": [[1, 2], [3, 4]], "b": [[1, 0], [0, 1]]}`
+	req := httptest.NewRequest("POST", "/matrix", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	MatrixHandler(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if hits.Load() == 0 {
+		t.Errorf("Expected the block to reach the failing worker")
+	}
+}
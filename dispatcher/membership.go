@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Estado de un worker dentro del pool del dispatcher.
+type WorkerState string
+
+const (
+	StateJoining  WorkerState = "joining"  // Registrado, aún sin responder al primer /ping
+	StateActive   WorkerState = "active"   // Recibe trabajo
+	StateSuspect  WorkerState = "suspect"  // Falló varias veces seguidas (ver HealthPolicy); no recibe trabajo
+	StateDraining WorkerState = "draining" // Se dio de baja; termina lo que tiene y se elimina
+	StateDead     WorkerState = "dead"     // Su lease expiró; se sondea con espera creciente y se elimina tras deadRetention
+)
+
+const (
+	// Duración de un lease. Lo renueva cada latido del worker o, si el worker
+	// no envía latidos (registro manual), cada sondeo correcto.
+	leaseTTL = 15 * time.Second
+
+	// Tiempo que un worker muerto sigue visible en /workers, y sondeándose con
+	// espera creciente por si vuelve, antes de eliminarse.
+	deadRetention = 2 * time.Minute
+)
+
+// Renueva el lease del worker. Requiere tener wk.mu.
+func (wk *WorkerInfo) renewLease(now time.Time) {
+	wk.LastSeen = now
+	wk.LeaseExpires = now.Add(leaseTTL)
+}
+
+// Indica si el worker puede recibir trabajo.
+func (wk *WorkerInfo) IsActive() bool {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+	return wk.State == StateActive
+}
+
+// Revisa los leases: un worker con el lease vencido pasa a muerto, un worker
+// muerto durante más de deadRetention se elimina y un worker que se está
+// drenando se elimina en la siguiente revisión.
+func sweepWorkers(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	kept := workers[:0]
+	for _, wk := range workers {
+		wk.mu.Lock()
+		evict := false
+		switch {
+		case wk.State == StateDraining:
+			evict = true
+		case wk.State == StateDead:
+			evict = now.After(wk.LeaseExpires.Add(deadRetention))
+		case now.After(wk.LeaseExpires):
+			wk.State = StateDead
+		}
+		wk.mu.Unlock()
+
+		if !evict {
+			kept = append(kept, wk)
+		}
+	}
+
+	// Limpia las referencias que quedan al final del slice
+	for i := len(kept); i < len(workers); i++ {
+		workers[i] = nil
+	}
+	workers = kept
+}
+
+// Añade la duración del lease a la respuesta, para que el worker ajuste sus latidos.
+func setLeaseHeader(w http.ResponseWriter) {
+	w.Header().Set("X-Lease-Ttl", strconv.Itoa(int(leaseTTL.Seconds())))
+}
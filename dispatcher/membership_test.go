@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetWorkers replaces the dispatcher's worker pool for a test.
+func resetWorkers(pool ...*WorkerInfo) {
+	mu.Lock()
+	defer mu.Unlock()
+	workers = pool
+}
+
+func TestSweepWorkers(t *testing.T) {
+	now := time.Now()
+	alive := &WorkerInfo{URL: "alive", State: StateActive, LeaseExpires: now.Add(time.Second)}
+	expired := &WorkerInfo{URL: "expired", State: StateActive, LeaseExpires: now.Add(-time.Second)}
+	recentlyDead := &WorkerInfo{URL: "recently-dead", State: StateDead, LeaseExpires: now.Add(-time.Second)}
+	longDead := &WorkerInfo{URL: "long-dead", State: StateDead, LeaseExpires: now.Add(-deadRetention - time.Second)}
+	draining := &WorkerInfo{URL: "draining", State: StateDraining, LeaseExpires: now.Add(time.Second)}
+	resetWorkers(alive, expired, recentlyDead, longDead, draining)
+	defer resetWorkers()
+
+	sweepWorkers(now)
+
+	mu.Lock()
+	defer mu.Unlock()
+	urls := make([]string, 0, len(workers))
+	for _, wk := range workers {
+		urls = append(urls, wk.URL)
+	}
+	if strings.Join(urls, ",") != "alive,expired,recently-dead" {
+		t.Errorf("Expected alive, expired and recently-dead to be kept, got %v", urls)
+	}
+	if expired.State != StateDead {
+		t.Errorf("Expected expired lease to mark the worker dead, got %s", expired.State)
+	}
+	if alive.State != StateActive {
+		t.Errorf("Expected valid lease to keep the worker active, got %s", alive.State)
+	}
+}
+
+func TestRegisterJoinsThenActivates(t *testing.T) {
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer worker.Close()
+	resetWorkers()
+	defer resetWorkers()
+
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"url":"`+worker.URL+`"}`))
+	rec := httptest.NewRecorder()
+	RegisterHandler(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Lease-Ttl") != "15" {
+		t.Errorf("Expected X-Lease-Ttl 15, got %q", rec.Header().Get("X-Lease-Ttl"))
+	}
+
+	// The registration probe moves the worker from joining to active
+	mu.Lock()
+	wk := workers[0]
+	mu.Unlock()
+	for i := 0; i < 50 && !wk.IsActive(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !wk.IsActive() {
+		t.Errorf("Expected worker to become active after answering /ping")
+	}
+}
+
+func TestHeartbeatRenewsLease(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	active := &WorkerInfo{URL: "http://active:8080", State: StateActive, LeaseExpires: past}
+	dead := &WorkerInfo{URL: "http://dead:8080", State: StateDead, LeaseExpires: past}
+	resetWorkers(active, dead)
+	defer resetWorkers()
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{active.URL, http.StatusNoContent},
+		{dead.URL, http.StatusNotFound},
+		{"http://unknown:8080", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/heartbeat", strings.NewReader(`{"url":"`+tt.url+`"}`))
+		rec := httptest.NewRecorder()
+		HeartbeatHandler(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.url, tt.status, rec.Code)
+		}
+	}
+
+	if !active.LeaseExpires.After(time.Now()) {
+		t.Errorf("Expected heartbeat to renew the lease")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Valor numérico de cada estado del circuit breaker en /metrics.
+var breakerStateValue = map[BreakerState]int{
+	BreakerClosed:   0,
+	BreakerHalfOpen: 1,
+	BreakerOpen:     2,
+}
+
+// MetricsHandler expone las métricas de los workers en formato de texto de
+// Prometheus: estado del pool y del circuit breaker, peticiones en curso y
+// completadas, las peticiones y errores de la ventana del breaker, el
+// presupuesto de reintentos y el hedging.
+func MetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	mu.Lock()
+	pool := make([]*WorkerInfo, len(workers))
+	copy(pool, workers)
+	mu.Unlock()
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metric(w, "dispatcher_worker_active", "gauge", "1 si el worker recibe trabajo.")
+	for _, wk := range pool {
+		active := 0
+		if wk.IsActive() {
+			active = 1
+		}
+		sample(w, "dispatcher_worker_active", wk.URL, active)
+	}
+
+	metric(w, "dispatcher_worker_breaker_state", "gauge", "Estado del circuit breaker: 0 cerrado, 1 semiabierto, 2 abierto.")
+	stats := make([]BreakerStats, len(pool))
+	for i, wk := range pool {
+		stats[i] = wk.breaker.stats(now)
+		sample(w, "dispatcher_worker_breaker_state", wk.URL, breakerStateValue[stats[i].State])
+	}
+
+	metric(w, "dispatcher_worker_breaker_opens_total", "counter", "Veces que se abrió el circuit breaker.")
+	for i, wk := range pool {
+		sample(w, "dispatcher_worker_breaker_opens_total", wk.URL, stats[i].Opens)
+	}
+
+	metric(w, "dispatcher_worker_window_requests", "gauge", "Peticiones en la ventana del circuit breaker.")
+	for i, wk := range pool {
+		sample(w, "dispatcher_worker_window_requests", wk.URL, stats[i].Requests)
+	}
+
+	metric(w, "dispatcher_worker_window_failures", "gauge", "Errores en la ventana del circuit breaker.")
+	for i, wk := range pool {
+		sample(w, "dispatcher_worker_window_failures", wk.URL, stats[i].Failures)
+	}
+
+	metric(w, "dispatcher_worker_outstanding", "gauge", "Peticiones del dispatcher en curso con el worker.")
+	for _, wk := range pool {
+		sample(w, "dispatcher_worker_outstanding", wk.URL, wk.outstanding.Load())
+	}
+
+	tokens, retries, denied := retryBudget.stats()
+	metric(w, "dispatcher_retry_budget_tokens", "gauge", "Reintentos disponibles en el presupuesto.")
+	fmt.Fprintf(w, "dispatcher_retry_budget_tokens %v\n", tokens)
+	metric(w, "dispatcher_retries_total", "counter", "Reintentos concedidos.")
+	fmt.Fprintf(w, "dispatcher_retries_total %d\n", retries)
+	metric(w, "dispatcher_retries_denied_total", "counter", "Reintentos denegados por falta de presupuesto.")
+	fmt.Fprintf(w, "dispatcher_retries_denied_total %d\n", denied)
+
+	metric(w, "dispatcher_hedges_total", "counter", "Peticiones duplicadas por hedging.")
+	fmt.Fprintf(w, "dispatcher_hedges_total %d\n", hedgesSent.Load())
+	metric(w, "dispatcher_hedges_won_total", "counter", "Duplicados que respondieron antes que el original.")
+	fmt.Fprintf(w, "dispatcher_hedges_won_total %d\n", hedgesWon.Load())
+
+	metric(w, "dispatcher_hedge_delay_seconds", "gauge", "Espera antes de duplicar una petición de cada ruta con hedging.")
+	for _, handler := range hedgePolicy.Routes {
+		route := handler.Method + " " + handler.Path
+		fmt.Fprintf(w, "dispatcher_hedge_delay_seconds{route=%q} %v\n", route, hedgePolicy.delay(route).Seconds())
+	}
+
+	metric(w, "dispatcher_worker_tasks_done_total", "counter", "Peticiones completadas por el worker.")
+	for _, wk := range pool {
+		wk.mu.Lock()
+		done := wk.TasksDone
+		wk.mu.Unlock()
+		sample(w, "dispatcher_worker_tasks_done_total", wk.URL, done)
+	}
+}
+
+// Escribe la cabecera de una métrica.
+func metric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Escribe el valor de una métrica para un worker.
+func sample(w io.Writer, name, worker string, value any) {
+	fmt.Fprintf(w, "%s{worker=%q} %v\n", name, worker, value)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Funciones comunes para cargar las políticas de las variables de entorno.
+// Si la variable no está definida, dejan el destino como está (el valor
+// predeterminado); si no es válida, devuelven un error con su nombre y valor.
+
+// Carga una duración (como "5s") mayor que cero.
+func envDuration(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("bad %s: %q", name, value)
+	}
+	*target = d
+	return nil
+}
+
+// Carga un entero no menor que min.
+func envInt(name string, target *int, min int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return fmt.Errorf("bad %s: %q", name, value)
+	}
+	*target = n
+	return nil
+}
+
+// Carga un número entre min y max, ambos incluidos.
+func envFloat(name string, target *float64, min, max float64) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < min || f > max {
+		return fmt.Errorf("bad %s: %q", name, value)
+	}
+	*target = f
+	return nil
+}
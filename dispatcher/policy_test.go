@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// withPolicy replaces a policy variable with value for the duration of a test.
+func withPolicy[T any](t *testing.T, target *T, value T) {
+	t.Helper()
+	saved := *target
+	t.Cleanup(func() { *target = saved })
+	*target = value
+}
+
+func TestEnvHelpers(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		load    func(name string) error
+		wantErr bool
+	}{
+		{"unset keeps default", "", func(name string) error { d := time.Second; return envDuration(name, &d) }, false},
+		{"duration", "2s", func(name string) error { var d time.Duration; return envDuration(name, &d) }, false},
+		{"zero duration", "0s", func(name string) error { var d time.Duration; return envDuration(name, &d) }, true},
+		{"bad duration", "soon", func(name string) error { var d time.Duration; return envDuration(name, &d) }, true},
+		{"int at minimum", "1", func(name string) error { var n int; return envInt(name, &n, 1) }, false},
+		{"int below minimum", "0", func(name string) error { var n int; return envInt(name, &n, 1) }, true},
+		{"float in range", "0.5", func(name string) error { var f float64; return envFloat(name, &f, 0, 1) }, false},
+		{"float above max", "1.5", func(name string) error { var f float64; return envFloat(name, &f, 0, 1) }, true},
+		{"unbounded float", "100", func(name string) error { var f float64; return envFloat(name, &f, 0, math.Inf(1)) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_POLICY_VALUE", tt.value)
+			if err := tt.load("TEST_POLICY_VALUE"); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v for %q, got %v", tt.wantErr, tt.value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Política de reintentos de las peticiones a los workers.
+type RetryPolicy struct {
+	MaxAttempts int             // Intentos por petición, incluido el primero
+	BaseBackoff time.Duration   // Espera antes del primer reintento; se duplica en cada uno
+	MaxBackoff  time.Duration   // Espera máxima entre intentos
+	Methods     map[string]bool // Métodos que se reintentan en cualquier ruta
+	Routes      []core.Handler  // Rutas sin efectos que se reintentan con su método, como las capacidades ("POST /matrix/part")
+
+	// Presupuesto global de reintentos: cada petición aporta BudgetRatio y cada
+	// reintento gasta 1, hasta un máximo de BudgetMax. Así los reintentos no
+	// superan una fracción del tráfico aunque todos los workers fallen a la vez.
+	BudgetRatio float64
+	BudgetMax   float64
+}
+
+// Devuelve la política predeterminada. Solo se reintentan los métodos seguros:
+// PUT y DELETE son idempotentes en HTTP, pero rutas como /deletefile responden
+// distinto la segunda vez. De los POST, solo /matrix/part, que calcula un
+// bloque sin efectos.
+func DefaultRetryPolicy() RetryPolicy {
+	routes, _ := parseCapabilities([]string{"POST /matrix/part"})
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 50 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Methods:     map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true, "TRACE": true},
+		Routes:      routes,
+		BudgetRatio: 0.2,
+		BudgetMax:   10,
+	}
+}
+
+// Política activa; main la carga del entorno con LoadRetryPolicy.
+var retryPolicy = DefaultRetryPolicy()
+
+// Carga la política desde las variables de entorno RETRY_MAX_ATTEMPTS (entero),
+// RETRY_BACKOFF, RETRY_MAX_BACKOFF (duraciones), RETRY_METHODS, RETRY_ROUTES
+// (listas separadas por comas, como "GET,HEAD" y "POST /matrix/part"),
+// RETRY_BUDGET_RATIO y RETRY_BUDGET_MAX (números).
+// Las variables ausentes conservan el valor predeterminado.
+func LoadRetryPolicy() (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+
+	if err := envInt("RETRY_MAX_ATTEMPTS", &policy.MaxAttempts, 1); err != nil {
+		return policy, err
+	}
+
+	durations := map[string]*time.Duration{
+		"RETRY_BACKOFF":     &policy.BaseBackoff,
+		"RETRY_MAX_BACKOFF": &policy.MaxBackoff,
+	}
+	for name, target := range durations {
+		if err := envDuration(name, target); err != nil {
+			return policy, err
+		}
+	}
+
+	floats := map[string]*float64{
+		"RETRY_BUDGET_RATIO": &policy.BudgetRatio,
+		"RETRY_BUDGET_MAX":   &policy.BudgetMax,
+	}
+	for name, target := range floats {
+		if err := envFloat(name, target, 0, math.Inf(1)); err != nil {
+			return policy, err
+		}
+	}
+
+	if value := os.Getenv("RETRY_METHODS"); value != "" {
+		policy.Methods = make(map[string]bool)
+		for _, method := range strings.Split(value, ",") {
+			policy.Methods[strings.ToUpper(strings.TrimSpace(method))] = true
+		}
+	}
+
+	if value := os.Getenv("RETRY_ROUTES"); value != "" {
+		routes, err := parseCapabilities(strings.Split(value, ","))
+		if err != nil {
+			return policy, fmt.Errorf("bad RETRY_ROUTES: %w", err)
+		}
+		policy.Routes = routes
+	}
+
+	return policy, nil
+}
+
+// Indica si una petición puede repetirse en otro worker tras llegar al primero:
+// su método es seguro, su ruta no tiene efectos o el cliente envía
+// Idempotency-Key, con la que cada worker descarta las repeticiones que recibe
+// (ver core.Idempotency).
+func (policy RetryPolicy) retryable(method, path string, headers http.Header) bool {
+	if policy.Methods[method] || headers.Get("Idempotency-Key") != "" {
+		return true
+	}
+	handler, _, _ := core.FindHandler(policy.Routes, method, path)
+	return handler != nil
+}
+
+// Espera antes del reintento número retry (1 = primero): BaseBackoff duplicado
+// en cada reintento hasta MaxBackoff, con una variación aleatoria entre la mitad
+// y el total para que los reintentos de muchas peticiones no coincidan.
+func (policy RetryPolicy) backoff(retry int) time.Duration {
+	wait := policy.BaseBackoff
+	for i := 1; i < retry && wait < policy.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, policy.MaxBackoff)
+
+	half := wait / 2
+	return half + rand.N(wait-half+1)
+}
+
+// Indica si el error ocurrió antes de enviar la petición (no se pudo conectar
+// con el worker), de modo que repetirla no puede duplicar sus efectos.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Presupuesto global de reintentos (ver RetryPolicy).
+type budget struct {
+	mu      sync.Mutex
+	tokens  float64
+	filled  bool  // El presupuesto empieza lleno
+	retries int64 // Reintentos concedidos desde el arranque
+	denied  int64 // Reintentos denegados por falta de presupuesto
+}
+
+var retryBudget budget
+
+// Suma la aportación de una petición nueva.
+func (b *budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fill()
+	b.tokens = min(b.tokens+retryPolicy.BudgetRatio, retryPolicy.BudgetMax)
+}
+
+// Gasta un reintento; devuelve false si no queda presupuesto.
+func (b *budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fill()
+	if b.tokens < 1 {
+		b.denied++
+		return false
+	}
+	b.tokens--
+	b.retries++
+	return true
+}
+
+// Devuelve un reintento concedido que al final no se hizo.
+func (b *budget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+1, retryPolicy.BudgetMax)
+	b.retries--
+}
+
+// Llena el presupuesto la primera vez que se usa. Requiere b.mu.
+func (b *budget) fill() {
+	if !b.filled {
+		b.tokens = retryPolicy.BudgetMax
+		b.filled = true
+	}
+}
+
+// Métricas del presupuesto de reintentos.
+func (b *budget) stats() (tokens float64, retries, denied int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fill()
+	return b.tokens, b.retries, b.denied
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withRetryPolicy replaces the retry policy with a fast one and resets the budget.
+func withRetryPolicy(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { retryBudget = budget{} })
+	retryBudget = budget{}
+
+	policy := DefaultRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	policy.MaxBackoff = 2 * time.Millisecond
+	withPolicy(t, &retryPolicy, policy)
+}
+
+// failingWorkers starts n workers that always answer 500 and counts their hits.
+func failingWorkers(t *testing.T, n int, hits *atomic.Int64) []*WorkerInfo {
+	t.Helper()
+	pool := make([]*WorkerInfo, n)
+	for i := range pool {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+		pool[i] = &WorkerInfo{URL: server.URL, State: StateActive}
+	}
+	resetWorkers(pool...)
+	t.Cleanup(func() { resetWorkers() })
+	SetStrategy(&roundRobin{})
+	return pool
+}
+
+func TestRetryOnlyIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		headers  http.Header
+		wantHits int64
+	}{
+		{"GET is retried", "GET", "/createfile", http.Header{}, 2},
+		{"POST is not retried", "POST", "/createfile", http.Header{}, 1},
+		{"DELETE is not retried", "DELETE", "/createfile", http.Header{}, 1},
+		{"POST with Idempotency-Key is retried", "POST", "/createfile", http.Header{"Idempotency-Key": []string{"k1"}}, 2},
+		{"POST to a side-effect-free route is retried", "POST", "/matrix/part", http.Header{}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRetryPolicy(t)
+			var hits atomic.Int64
+			failingWorkers(t, 2, &hits)
+
+			resp, err := DoRequestWithRetry(context.Background(), tt.method, tt.url, nil, tt.headers)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if hits.Load() != tt.wantHits {
+				t.Errorf("Expected %d attempts, got %d", tt.wantHits, hits.Load())
+			}
+		})
+	}
+}
+
+func TestNonRetriedFailureReturnsWorkerResponse(t *testing.T) {
+	withRetryPolicy(t)
+	var hits atomic.Int64
+	failingWorkers(t, 2, &hits)
+
+	resp, err := DoRequestWithRetry(context.Background(), "POST", "/createfile", nil, http.Header{})
+	if err != nil {
+		t.Fatalf("Expected the worker response, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	}
+}
+
+func TestRetryUnsentRequest(t *testing.T) {
+	withRetryPolicy(t)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("created"))
+	}))
+	defer up.Close()
+
+	resetWorkers(&WorkerInfo{URL: down.URL, State: StateActive}, &WorkerInfo{URL: up.URL, State: StateActive})
+	defer resetWorkers()
+	SetStrategy(&roundRobin{})
+
+	// A POST that never reached the first worker is safe to send to the second
+	resp, err := DoRequestWithRetry(context.Background(), "POST", "/createfile", nil, http.Header{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestRetryBudget(t *testing.T) {
+	withRetryPolicy(t)
+	retryPolicy.BudgetRatio = 0
+	retryPolicy.BudgetMax = 1
+	var hits atomic.Int64
+	failingWorkers(t, 2, &hits)
+
+	for i := 0; i < 2; i++ {
+		resp, err := DoRequestWithRetry(context.Background(), "GET", "/hash", nil, http.Header{})
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	// Only the first request could retry
+	if hits.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", hits.Load())
+	}
+	if tokens, retries, denied := retryBudget.stats(); tokens != 0 || retries != 1 || denied != 1 {
+		t.Errorf("Expected 0 tokens, 1 retry and 1 denied, got %v %d %d", tokens, retries, denied)
+	}
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "dispatcher_retries_denied_total 1") {
+		t.Errorf("Expected denied retries in metrics, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.BaseBackoff = 100 * time.Millisecond
+	policy.MaxBackoff = 300 * time.Millisecond
+
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 150 * time.Millisecond, 300 * time.Millisecond},
+		{10, 150 * time.Millisecond, 300 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if wait := policy.backoff(tt.retry); wait < tt.min || wait > tt.max {
+				t.Fatalf("retry %d: expected backoff in [%v, %v], got %v", tt.retry, tt.min, tt.max, wait)
+			}
+		}
+	}
+}
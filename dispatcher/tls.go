@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Clientes HTTP hacia los workers. Con mTLS activo presentan el certificado
+// del dispatcher y solo confían en la CA configurada.
+var (
+	httpClient   = &http.Client{}
+	healthClient = &http.Client{Timeout: 2 * time.Second}
+
+	// Si es true, /register y /unregister exigen un certificado de cliente
+	// válido cuyo nombre coincida con el host del worker.
+	requireWorkerCert bool
+
+	// Token que autoriza las rutas de administración ("Authorization: Bearer
+	// <token>"); main lo carga de ADMIN_TOKEN.
+	adminToken string
+)
+
+// Lee TLS_CERT_FILE, TLS_KEY_FILE y TLS_CA_FILE y, si están definidas, activa
+// mTLS: los clientes hacia los workers usan el certificado del dispatcher y
+// la configuración devuelta sirve para escuchar por HTTPS. Devuelve nil sin mTLS.
+func setupTLS() (*tls.Config, error) {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	caFile := os.Getenv("TLS_CA_FILE")
+
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, errors.New("TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load certificate: %w", err)
+	}
+
+	cas, err := core.LoadCertPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      cas,
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+	httpClient.Transport = transport
+	healthClient.Transport = transport
+	requireWorkerCert = true
+
+	// Los usuarios del dispatcher no necesitan certificado; los workers lo presentan al registrarse.
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    cas,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Comprueba que quien llama a /register o /unregister es el worker de workerURL:
+// su certificado, verificado contra la CA, debe ser válido para el host de la URL.
+// Sin mTLS acepta cualquier llamada.
+func verifyWorkerIdentity(r *http.Request, workerURL string) error {
+	if !requireWorkerCert {
+		return nil
+	}
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return errors.New("client certificate required")
+	}
+
+	parsed, err := url.Parse(workerURL)
+	if err != nil || parsed.Hostname() == "" {
+		return fmt.Errorf("bad worker url: %q", workerURL)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("worker url must use https: %q", workerURL)
+	}
+
+	leaf := r.TLS.VerifiedChains[0][0]
+	if err := leaf.VerifyHostname(parsed.Hostname()); err != nil {
+		return fmt.Errorf("certificate does not match worker: %w", err)
+	}
+
+	return nil
+}
+
+// Protege una ruta de administración: solo la atiende si quien llama presenta
+// un certificado de cliente verificado (con mTLS) o el token de ADMIN_TOKEN.
+// Sin mTLS ni token configurados, la ruta queda desactivada.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if status, err := verifyAdmin(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Comprueba las credenciales de una petición de administración y, si no
+// bastan, devuelve el código de estado con que rechazarla.
+func verifyAdmin(r *http.Request) (int, error) {
+	if requireWorkerCert && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return http.StatusOK, nil
+	}
+	if adminToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			return http.StatusOK, nil
+		}
+		return http.StatusUnauthorized, errors.New("admin token required")
+	}
+	if requireWorkerCert {
+		return http.StatusUnauthorized, errors.New("client certificate required")
+	}
+	return http.StatusForbidden, errors.New("admin routes disabled: set ADMIN_TOKEN or enable mTLS")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// TestRegisterVerifiesWorkerIdentity checks that, with mTLS enabled, /register only
+// accepts workers whose verified client certificate matches the registered host.
+func TestRegisterVerifiesWorkerIdentity(t *testing.T) {
+	cert, err := core.SelfSignedCertificate("worker1")
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert.Leaf}}}
+
+	mu.Lock()
+	workers = nil
+	mu.Unlock()
+	requireWorkerCert = true
+	defer func() {
+		requireWorkerCert = false
+		mu.Lock()
+		workers = nil
+		mu.Unlock()
+	}()
+
+	tests := []struct {
+		name   string
+		url    string
+		state  *tls.ConnectionState
+		status int
+	}{
+		{"matching certificate", "https://worker1:8080", verified, http.StatusNoContent},
+		{"other host", "https://rogue:8080", verified, http.StatusForbidden},
+		{"plain http", "http://worker1:8080", verified, http.StatusForbidden},
+		{"no certificate", "https://worker1:8080", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"url":"`+tt.url+`"}`))
+			req.TLS = tt.state
+			rec := httptest.NewRecorder()
+
+			RegisterHandler(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d (%s)", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(workers) != 1 || workers[0].URL != "https://worker1:8080" {
+		t.Errorf("Expected only the verified worker to be registered, got %d workers", len(workers))
+	}
+}
+
+// TestRequireAdmin checks that admin routes need the admin token or, with mTLS,
+// a verified client certificate, and are disabled when neither is configured.
+func TestRequireAdmin(t *testing.T) {
+	cert, err := core.SelfSignedCertificate("operator")
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert.Leaf}}}
+	defer func() {
+		adminToken = ""
+		requireWorkerCert = false
+	}()
+
+	tests := []struct {
+		name   string
+		token  string
+		mtls   bool
+		auth   string
+		state  *tls.ConnectionState
+		status int
+	}{
+		{"not configured", "", false, "Bearer secret", nil, http.StatusForbidden},
+		{"valid token", "secret", false, "Bearer secret", nil, http.StatusOK},
+		{"wrong token", "secret", false, "Bearer guess", nil, http.StatusUnauthorized},
+		{"missing token", "secret", false, "", nil, http.StatusUnauthorized},
+		{"verified certificate", "", true, "", verified, http.StatusOK},
+		{"no certificate", "", true, "", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adminToken = tt.token
+			requireWorkerCert = tt.mtls
+			req := httptest.NewRequest("GET", "/admin/strategy", nil)
+			req.TLS = tt.state
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			requireAdmin(StrategyHandler)(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d (%s)", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -37,9 +37,9 @@ func TestNotFoundRoute(t *testing.T) {
 
 func TestBadMethod(t *testing.T) {
     status := sendStatusLine(t,
-        "POST /fibonacci?num=5 HTTP/1.0\r\nHost: test\r\n\r\n",
+        "POST /fibonacci?num=5 HTTP/1.0\r\nHost: test\r\nContent-Length: 0\r\n\r\n",
     )
-    if !strings.Contains(status, "400") {
-        t.Errorf("Expected 400 Bad Request, got %q", status)
+    if !strings.Contains(status, "405") {
+        t.Errorf("Expected 405 Method Not Allowed, got %q", status)
     }
 }
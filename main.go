@@ -13,6 +13,9 @@ func main() {
 	// Crea una nueva instancia del servidor HTTP.
 	server := core.NewHttpServer()
 
+	// Middlewares globales: registro de solicitudes y conteo de conexiones.
+	server.Use(core.Logger, advanced.CountConnections)
+
 	// Registra un manejador para la ruta GET "/fibonacci".
 	server.Get("/fibonacci", service.FibonacciHandler)
 
@@ -26,6 +29,10 @@ func main() {
 	// También exponer "/deletefile" por GET para pruebas manuales sin body.
 	server.Get("/deletefile", service.DeleteFileHandler)
 
+	// Las mismas operaciones con el nombre del archivo en la ruta
+	server.Post("/files/{name}", service.CreateFileHandler)
+	server.Delete("/files/{name}", service.DeleteFileHandler)
+
 	// Endpoints de cadenas
 	server.Get("/reverse", handlers.ReverseHandler)
 	server.Get("/toupper", handlers.ToUpperHandler)
@@ -38,7 +45,7 @@ func main() {
 	server.Get("/simulate", advanced.SimulateHandler)
 	server.Get("/sleep", advanced.SleepHandler)
 	server.Get("/loadtest", advanced.LoadTestHandler)
-	server.Get("/status", advanced.StatusHandler)
+	server.Get("/status", advanced.NewStatusHandler(server.Stats))
 	server.Get("/help", advanced.HelpHandler)
 
 	// Inicia el servidor en el puerto 8081.
@@ -2,72 +2,46 @@ package router
 
 import (
 	"github.com/KateGF/Http-Server-Project-SO/core"
-	"sort"
-	"strings"
 )
 
 // Handle es el tipo de función que atiende una petición.
 type Handle = func(*core.HttpRequest) (*core.HttpResponse, error)
 
 // Route almacena un método, ruta y su handler.
-type Route struct {
-	Method string
-	Path   string
-	Handle Handle
-}
+// La ruta admite parámetros ("/files/{name}") y un comodín final ("/static/*").
+type Route = core.Handler
 
 // Router mantiene la lista de rutas.
+// Usa la misma tabla de rutas que core.HttpServer, así que implementa core.Router
+// y puede asignarse a server.Router.
 type Router struct {
-	routes []Route
+	*core.RouteTable
 }
 
 // New crea un Router vacío.
 func New() *Router {
-	return &Router{routes: make([]Route, 0)}
+	return &Router{core.NewRouteTable()}
 }
 
 // Get registra una ruta GET.
 func (r *Router) Get(path string, h Handle) {
-	r.routes = append(r.routes, Route{"GET", path, h})
+	r.AddRoute("GET", path, h)
 }
 
 // Post, Delete… (idéntico a Get, cambiando Method)
 func (r *Router) Post(path string, h Handle) {
-	r.routes = append(r.routes, Route{"POST", path, h})
+	r.AddRoute("POST", path, h)
 }
 func (r *Router) Delete(path string, h Handle) {
-	r.routes = append(r.routes, Route{"DELETE", path, h})
+	r.AddRoute("DELETE", path, h)
 }
 
-// match comprueba coincidencia exacta o prefijo (si path termina en '/').
+// match comprueba coincidencia exacta, de patrón o de prefijo (ver core.MatchPattern).
 func match(reqPath, routePath string) bool {
-	if reqPath == routePath {
-		return true
-	}
-	if !strings.HasSuffix(routePath, "/") {
-		routePath += "/"
-	}
-	return strings.HasPrefix(reqPath, routePath)
+	return core.MatchPath(reqPath, routePath)
 }
 
-// Handle despacha la HttpRequest al handler adecuado o retorna 404.
+// Handle despacha la HttpRequest al handler más específico (ver core.RouteTable.Route).
 func (r *Router) Handle(req *core.HttpRequest) (*core.HttpResponse, error) {
-	for _, rt := range r.routes {
-		if req.Method == rt.Method && match(req.Target.Path, rt.Path) {
-			return rt.Handle(req)
-		}
-	}
-	return core.NotFound().Text("no route"), nil
-}
-
-// SortHandlers ordena las rutas por número de segmentos (‘/’) y luego por longitud.
-func (r *Router) SortHandlers() {
-	sort.Slice(r.routes, func(i, j int) bool {
-		ic := strings.Count(r.routes[i].Path, "/")
-		jc := strings.Count(r.routes[j].Path, "/")
-		if ic != jc {
-			return ic > jc
-		}
-		return len(r.routes[i].Path) > len(r.routes[j].Path)
-	})
+	return r.Route(req)
 }
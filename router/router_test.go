@@ -46,8 +46,12 @@ func TestMethodMismatch(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Handle devolvió error: %v", err)
 	}
-	if res.StatusCode != 404 {
-		t.Errorf("Esperaba 404 por método incorrecto; obtuve %d", res.StatusCode)
+	// Misma semántica que core.HttpServer: ruta conocida con método incorrecto
+	if res.StatusCode != 405 {
+		t.Errorf("Esperaba 405 por método incorrecto; obtuve %d", res.StatusCode)
+	}
+	if res.Headers.Get("Allow") != "OPTIONS, POST" {
+		t.Errorf("Esperaba Allow 'OPTIONS, POST'; obtuve %q", res.Headers.Get("Allow"))
 	}
 }
 
@@ -127,7 +131,8 @@ func TestSortHandlersOrder(t *testing.T) {
 	r.Get("/a/b/c", h)
 	r.Get("/a/b", h)
 	r.SortHandlers()
-	paths := []string{r.routes[0].Path, r.routes[1].Path, r.routes[2].Path}
+	routes := r.Routes()
+	paths := []string{routes[0].Path, routes[1].Path, routes[2].Path}
 	want := []string{"/a/b/c", "/a/b", "/a"}
 	for i := range want {
 		if paths[i] != want[i] {
@@ -153,3 +158,55 @@ func TestDeleteAndPostRoutes(t *testing.T) {
 		t.Errorf("Esperaba 'POST'; obtuve %q", resPost.Body)
 	}
 }
+
+func TestPathParams(t *testing.T) {
+	r := New()
+	r.Get("/jobs/{id}/result", func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok().Text("job " + req.Param("id")), nil
+	})
+	res, err := r.Handle(makeReq("GET", "/jobs/42/result"))
+	if err != nil {
+		t.Fatalf("Handle devolvió error: %v", err)
+	}
+	if res.Body != "job 42" {
+		t.Errorf("Esperaba 'job 42'; obtuve %q", res.Body)
+	}
+}
+
+func TestWildcardRoute(t *testing.T) {
+	r := New()
+	r.Get("/static/*", func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok().Text(req.Param("*")), nil
+	})
+	r.Get("/static/index.html", func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok().Text("INDEX"), nil
+	})
+	res, _ := r.Handle(makeReq("GET", "/static/css/site.css"))
+	if res.Body != "css/site.css" {
+		t.Errorf("Esperaba 'css/site.css'; obtuve %q", res.Body)
+	}
+	// La ruta literal tiene precedencia sobre el comodín, aunque se registre después
+	res, _ = r.Handle(makeReq("GET", "/static/index.html"))
+	if res.Body != "INDEX" {
+		t.Errorf("Esperaba 'INDEX'; obtuve %q", res.Body)
+	}
+}
+
+func TestRouterAsServerRouter(t *testing.T) {
+	r := New()
+	r.Get("/foo", func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok().Text("FOO"), nil
+	})
+	server := core.NewHttpServer()
+	server.Router = r
+	server.Get("/bar", func(req *core.HttpRequest) (*core.HttpResponse, error) {
+		return core.Ok().Text("BAR"), nil
+	})
+	if len(r.Routes()) != 2 {
+		t.Fatalf("Esperaba 2 rutas en el Router inyectado; obtuve %d", len(r.Routes()))
+	}
+	res, _ := r.Handle(makeReq("GET", "/bar"))
+	if res.Body != "BAR" {
+		t.Errorf("Esperaba 'BAR'; obtuve %q", res.Body)
+	}
+}
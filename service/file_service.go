@@ -96,12 +96,22 @@ func DeleteFile(filename string) error {
 	return nil
 }
 
+// Obtiene el nombre del archivo desde el parámetro de ruta {name} (ej. "/files/{name}")
+// o, si la ruta no lo define, desde el parámetro 'name' de la consulta.
+func fileName(request *core.HttpRequest) string {
+	if name := request.Param("name"); name != "" {
+		return name
+	}
+	return request.Target.Query().Get("name")
+}
+
 // Maneja las solicitudes HTTP para crear archivos.
 // Extrae los parámetros 'name', 'content' y 'repeat' de la consulta y valida los parámetros.
+// El nombre también puede venir en la ruta (ver fileName).
 // Devuelve una respuesta HTTP indicando éxito o error.
 func CreateFileHandler(request *core.HttpRequest) (*core.HttpResponse, error) {
 	// Obtener parámetros de la consulta
-	name := request.Target.Query().Get("name")
+	name := fileName(request)
 	if name == "" {
 		return core.BadRequest().Text("name is required"), nil
 	}
@@ -140,11 +150,11 @@ func CreateFileHandler(request *core.HttpRequest) (*core.HttpResponse, error) {
 }
 
 // Maneja las solicitudes HTTP para eliminar archivos.
-// Extrae el parámetro 'name' de la consulta y valida el parámetro.
+// Extrae el parámetro 'name' de la ruta o de la consulta y valida el parámetro.
 // Devuelve una respuesta HTTP indicando éxito o error.
 func DeleteFileHandler(request *core.HttpRequest) (*core.HttpResponse, error) {
-	// Obtener el parámetro 'name' de la consulta
-	name := request.Target.Query().Get("name")
+	// Obtener el parámetro 'name' de la ruta o de la consulta
+	name := fileName(request)
 	if name == "" {
 		return core.BadRequest().Text("name is required"), nil
 	}
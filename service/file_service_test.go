@@ -127,3 +127,27 @@ func TestDeleteFileHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteFileHandlerPathParam(t *testing.T) {
+	// Before
+	name := "path_param_test.txt"
+	os.WriteFile(name, []byte("test"), os.ModePerm)
+	defer Clean(t, name)
+
+	// Arrange
+	target, _ := url.Parse("/files/" + name)
+	request := core.NewHttpRequest("DELETE", target, map[string]string{}, "")
+	request.Params = map[string]string{"name": name}
+
+	// Act
+	response, _ := DeleteFileHandler(request)
+
+	// Assert
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status code to be 200, not %d", response.StatusCode)
+	}
+
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("Expected file %s to not exist ", name)
+	}
+}
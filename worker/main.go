@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/KateGF/Http-Server-Project-SO/advanced"
 	"github.com/KateGF/Http-Server-Project-SO/core"
@@ -16,14 +24,33 @@ func pingHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 }
 
 func main() {
-    server := core.NewHttpServer()
+    dispatcherURL := flag.String("dispatcher", os.Getenv("DISPATCHER_URL"), "URL del dispatcher donde registrarse (vacío = sin registro)")
+    advertise := flag.String("advertise", os.Getenv("WORKER_URL"), "URL con la que el dispatcher llega a este worker (por defecto, el hostname)")
+    flag.Parse()
+
+    // El worker controla su propio ciclo de vida (ver waitForSignal).
+    config := core.DefaultConfig()
+    config.HandleSignals = false
+
+    server := core.NewHttpServerWithConfig(config)
+    // Las repeticiones con la misma Idempotency-Key reciben la respuesta guardada
+    server.Use(core.Logger, advanced.CountConnections, core.Idempotency(10*time.Minute))
+
+    // Los trabajos pesados comparten 8 turnos (y 16 en espera); las rutas de
+    // control nunca esperan, así el dispatcher siempre recibe respuesta a /ping.
+    // 8 + 16 < config.Workers deja workers libres para las rutas de control.
+    scheduler := core.NewScheduler(8, 16, 5*time.Second)
+    heavy := scheduler.Priority(core.PriorityLow)
+    control := scheduler.Priority(core.PriorityHigh)
 
     // Rutas originales (tal como en tu main.go)
-    server.Get("/fibonacci", service.FibonacciHandler)
+    server.Get("/fibonacci", service.FibonacciHandler, heavy)
     server.Post("/createfile", service.CreateFileHandler)
     server.Get("/createfile", service.CreateFileHandler)
     server.Delete("/deletefile", service.DeleteFileHandler)
     server.Get("/deletefile", service.DeleteFileHandler)
+    server.Post("/files/{name}", service.CreateFileHandler)
+    server.Delete("/files/{name}", service.DeleteFileHandler)
 
     server.Get("/reverse", handlers.ReverseHandler)
     server.Get("/toupper", handlers.ToUpperHandler)
@@ -32,19 +59,109 @@ func main() {
 
     server.Get("/random", advanced.RandomHandler)
     server.Get("/timestamp", advanced.TimestampHandler)
-    server.Get("/simulate", advanced.SimulateHandler)
-    server.Get("/sleep", advanced.SleepHandler)
-    server.Get("/loadtest", advanced.LoadTestHandler)
-    server.Get("/status", advanced.StatusHandler)
-    server.Get("/help", advanced.HelpHandler)
+    server.Get("/simulate", advanced.SimulateHandler, core.ConcurrencyLimit(4), heavy)
+    server.Get("/sleep", advanced.SleepHandler, core.ConcurrencyLimit(4), heavy)
+    server.Get("/loadtest", advanced.LoadTestHandler, core.ConcurrencyLimit(2), heavy)
+    server.Get("/status", advanced.NewStatusHandler(server.Stats), control)
+    server.Get("/help", advanced.HelpHandler, control)
 
     // --- Nuevos endpoints para procesamiento distribuido ---
-    server.Get("/ping", pingHandler)
-    server.Get("/pi/part", piPartHandler)               // definido más abajo
-    server.Post("/matrix/part", matrix.MatrixHandler)   // definido más abajo
+    server.Get("/ping", pingHandler, control)
+    server.Get("/pi/part", piPartHandler, heavy)               // definido más abajo
+    server.Post("/matrix/part", matrix.MatrixHandler, heavy)   // definido más abajo
 
-    slog.Info("Worker arrancado en :8080")
-    if err := server.Start(8080); err != nil {
+    serverTLS, clientTLS, err := loadTLS()
+    if err != nil {
         slog.Error("Worker error", "err", err)
+        os.Exit(1)
+    }
+
+    // Con un dispatcher configurado, el worker se registra solo y envía latidos.
+    var reg *registration
+    if *dispatcherURL != "" {
+        self := *advertise
+        if self == "" {
+            scheme := "http"
+            if serverTLS != nil {
+                scheme = "https"
+            }
+            self = defaultAdvertiseURL(scheme, 8080)
+        }
+
+        reg = newRegistration(*dispatcherURL, self, server.Routes(), 5*time.Second, dispatcherClient(clientTLS))
+        go reg.run()
+    }
+
+    go waitForSignal(server, reg, 15*time.Second)
+
+    if err := listen(server, serverTLS); err != nil {
+        slog.Error("Worker error", "err", err)
+    }
+}
+
+// waitForSignal espera SIGINT/SIGTERM, da de baja al worker en el dispatcher
+// (si está registrado) y cierra el servidor drenando las peticiones en curso
+// durante como máximo 'timeout'.
+func waitForSignal(server *core.HttpServer, reg *registration, timeout time.Duration) {
+    sigCh := make(chan os.Signal, 1)
+    signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+    <-sigCh
+
+    // Primero la baja, para que el dispatcher deje de enviar trabajo.
+    if reg != nil {
+        reg.unregister()
+    }
+
+    slog.Info("Worker cerrando", "timeout", timeout)
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+
+    if err := server.Shutdown(ctx); err != nil {
+        slog.Error("Cierre forzado", "err", err)
     }
 }
+
+// loadTLS lee TLS_CERT_FILE, TLS_KEY_FILE y TLS_CA_FILE. Si están definidas
+// devuelve la configuración mTLS del servidor (solo acepta clientes con un
+// certificado de esa CA) y la del cliente hacia el dispatcher. Sin ellas devuelve nil.
+func loadTLS() (*tls.Config, *tls.Config, error) {
+    certFile := os.Getenv("TLS_CERT_FILE")
+    keyFile := os.Getenv("TLS_KEY_FILE")
+    caFile := os.Getenv("TLS_CA_FILE")
+
+    if certFile == "" && keyFile == "" && caFile == "" {
+        return nil, nil, nil
+    }
+    if certFile == "" || keyFile == "" || caFile == "" {
+        return nil, nil, errors.New("TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE must be set together")
+    }
+
+    serverTLS, err := core.LoadTLSConfig(certFile, keyFile, caFile)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    cas, err := core.LoadCertPool(caFile)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    clientTLS := &tls.Config{
+        Certificates: serverTLS.Certificates,
+        RootCAs:      cas,
+        MinVersion:   tls.VersionTLS12,
+    }
+
+    return serverTLS, clientTLS, nil
+}
+
+// listen atiende en :8080, con mTLS si tlsConfig no es nil.
+func listen(server *core.HttpServer, tlsConfig *tls.Config) error {
+    if tlsConfig == nil {
+        slog.Info("Worker arrancado en :8080")
+        return server.Start(8080)
+    }
+
+    slog.Info("Worker arrancado en :8080 (mTLS)")
+    return server.ListenAndServeTLS("tcp", ":8080", tlsConfig)
+}
@@ -1,6 +1,7 @@
 package matrix
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 )
@@ -21,6 +22,12 @@ func NewMatrix(data [][]float64) (Matrix, error) {
 
 // Multiply realiza la multiplicación de dos matrices y devuelve una nueva matriz resultante.
 func (a Matrix) Multiply(b Matrix) (Matrix, error) {
+	return a.MultiplyContext(context.Background(), b)
+}
+
+// MultiplyContext es como Multiply, pero abandona el cálculo con el error de
+// ctx si este termina antes (se comprueba en cada fila del resultado).
+func (a Matrix) MultiplyContext(ctx context.Context, b Matrix) (Matrix, error) {
 	if len(a.data[0]) != len(b.data) {
 		return Matrix{}, errors.New("the number of columns in the first matrix must be equal to the number of rows in the second matrix")
 	}
@@ -28,6 +35,9 @@ func (a Matrix) Multiply(b Matrix) (Matrix, error) {
 	result := make([][]float64, len(a.data))
 
 	for i := range result {
+		if err := ctx.Err(); err != nil {
+			return Matrix{}, err
+		}
 		result[i] = make([]float64, len(b.data[0]))
 
 		for j := range result[i] {
@@ -42,8 +42,12 @@ func MatrixHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
 		return core.BadRequest().Text(err.Error()), nil
 	}
 
-	matrix, err := matrices.A.Multiply(matrices.B)
+	// El cálculo se abandona si el cliente se va o vence el plazo de la solicitud
+	matrix, err := matrices.A.MultiplyContext(req.Context(), matrices.B)
 	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
 		return core.BadRequest().Text(err.Error()), nil
 	}
 
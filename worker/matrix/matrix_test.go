@@ -1,7 +1,9 @@
 package matrix
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -49,6 +51,22 @@ func TestMultiply_Error(t *testing.T) {
 	}
 }
 
+func TestMultiplyContext_Cancelled(t *testing.T) {
+	// Arrange
+	a, _ := NewMatrix([][]float64{{1, 2}, {3, 4}})
+	b, _ := NewMatrix([][]float64{{5, 6}, {7, 8}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, err := a.MultiplyContext(ctx, b)
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 func TestToJson(t *testing.T) {
 	// Arrange
 	m, _ := NewMatrix([][]float64{{1.5, 2.5}, {3.5, 4.5}})
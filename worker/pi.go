@@ -8,6 +8,9 @@ import (
     "github.com/KateGF/Http-Server-Project-SO/core"
 )
 
+// Iteraciones entre comprobaciones del contexto de la solicitud
+const piCheckEvery = 1 << 16
+
 // piPartHandler fragmenta el cálculo de π según ?iter=n
 func piPartHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
     iterStr := req.Target.Query().Get("iter")
@@ -16,9 +19,14 @@ func piPartHandler(req *core.HttpRequest) (*core.HttpResponse, error) {
         return core.BadRequest().Text("invalid 'iter' parameter"), nil
     }
 
+    ctx := req.Context()
     rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
     inside := 0
     for i := 0; i < iter; i++ {
+        // cada tanto se comprueba si el cliente se fue o venció el plazo
+        if i%piCheckEvery == 0 && ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
         x := rnd.Float64()
         y := rnd.Float64()
         if x*x+y*y <= 1 {
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// Mantiene el registro del worker en el dispatcher: se registra al arrancar,
+// envía latidos periódicos y se da de baja al cerrar.
+type registration struct {
+	dispatcher   string   // URL base del dispatcher (ej. http://dispatcher:8000)
+	self         string   // URL con la que el dispatcher llega a este worker
+	capabilities []string // Rutas que atiende el worker ("GET /ping", ...)
+	interval     time.Duration
+	client       *http.Client
+
+	stop chan struct{} // Se cierra para detener los latidos
+	done chan struct{} // Se cierra cuando el bucle de latidos termina
+}
+
+// Crea el registro con las rutas del servidor como capacidades.
+func newRegistration(dispatcher, self string, routes []core.Handler, interval time.Duration, client *http.Client) *registration {
+	capabilities := make([]string, 0, len(routes))
+	for _, route := range routes {
+		capabilities = append(capabilities, route.Method+" "+route.Path)
+	}
+
+	return &registration{
+		dispatcher:   dispatcher,
+		self:         self,
+		capabilities: capabilities,
+		interval:     interval,
+		client:       client,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Se registra (reintentando con espera creciente hasta lograrlo) y envía latidos
+// hasta que se llama a unregister. Si el dispatcher ya no conoce al worker
+// (por ejemplo, porque se reinició), vuelve a registrarse.
+func (r *registration) run() {
+	defer close(r.done)
+
+	registered := false
+	backoff := time.Second
+
+	for {
+		var err error
+		if !registered {
+			var header http.Header
+			header, err = r.post("/register", map[string]any{"url": r.self, "capabilities": r.capabilities})
+			if err == nil {
+				slog.Info("Worker registrado", "dispatcher", r.dispatcher, "url", r.self)
+				registered = true
+				backoff = time.Second
+				r.adjustInterval(header)
+			}
+		} else if _, err = r.post("/heartbeat", map[string]any{"url": r.self}); errors.Is(err, errUnknownWorker) {
+			slog.Warn("El dispatcher no reconoce al worker; se registra de nuevo")
+			registered = false
+			continue
+		}
+
+		wait := r.interval
+		if err != nil {
+			slog.Warn("Error contactando al dispatcher", "err", err, "retry_in", backoff)
+			wait = backoff
+			backoff = min(backoff*2, 30*time.Second)
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Detiene los latidos y da de baja al worker en el dispatcher.
+func (r *registration) unregister() {
+	close(r.stop)
+	<-r.done
+
+	if _, err := r.post("/unregister", map[string]any{"url": r.self}); err != nil {
+		slog.Error("Error al darse de baja", "err", err)
+		return
+	}
+	slog.Info("Worker dado de baja", "dispatcher", r.dispatcher)
+}
+
+// Error devuelto cuando el dispatcher responde 404 a un latido.
+var errUnknownWorker = errors.New("worker not registered")
+
+// Ajusta el intervalo de latidos a un tercio del lease que anuncia el dispatcher
+// (cabecera X-Lease-Ttl, en segundos), para renovarlo antes de que expire
+// aunque se pierda algún latido.
+func (r *registration) adjustInterval(header http.Header) {
+	seconds, err := strconv.Atoi(header.Get("X-Lease-Ttl"))
+	if err != nil || seconds <= 0 {
+		return
+	}
+
+	if interval := time.Duration(seconds) * time.Second / 3; interval < r.interval {
+		r.interval = interval
+	}
+}
+
+// Envía un POST con cuerpo JSON al dispatcher y devuelve las cabeceras de la respuesta.
+func (r *registration) post(path string, payload any) (http.Header, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Post(r.dispatcher+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errUnknownWorker
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", path, resp.Status)
+	}
+
+	return resp.Header, nil
+}
+
+// Crea el cliente hacia el dispatcher. Con mTLS presenta el certificado del worker,
+// que el dispatcher usa para verificar su identidad al registrarlo.
+func dispatcherClient(tlsConfig *tls.Config) *http.Client {
+	client := &http.Client{Timeout: 5 * time.Second}
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return client
+}
+
+// Devuelve la URL con la que el dispatcher llega a este worker: el nombre del
+// host (en Docker, el del contenedor) y el puerto dado.
+func defaultAdvertiseURL(scheme string, port int) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, port)
+}
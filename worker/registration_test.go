@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KateGF/Http-Server-Project-SO/core"
+)
+
+// TestRegistrationLifecycle checks that the worker registers with its capabilities,
+// re-registers when the dispatcher forgets it, and unregisters on shutdown.
+func TestRegistrationLifecycle(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		calls        []string
+		capabilities []string
+		known        bool
+	)
+
+	dispatcher := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			URL          string
+			Capabilities []string
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.URL.Path)
+
+		switch r.URL.Path {
+		case "/register":
+			capabilities = payload.Capabilities
+			known = true
+		case "/heartbeat":
+			// El primer latido simula un dispatcher reiniciado que olvidó al worker
+			if len(calls) == 2 {
+				known = false
+			}
+			if !known {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer dispatcher.Close()
+
+	routes := []core.Handler{{Method: "GET", Path: "/ping"}}
+	reg := newRegistration(dispatcher.URL, "http://worker1:8080", routes, 10*time.Millisecond, dispatcherClient(nil))
+	go reg.run()
+
+	time.Sleep(100 * time.Millisecond)
+	reg.unregister()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(calls) < 4 || calls[0] != "/register" || calls[1] != "/heartbeat" || calls[2] != "/register" {
+		t.Fatalf("Expected register, heartbeat, register again, got %v", calls)
+	}
+	if calls[len(calls)-1] != "/unregister" {
+		t.Errorf("Expected last call to be /unregister, got %v", calls)
+	}
+	if len(capabilities) != 1 || capabilities[0] != "GET /ping" {
+		t.Errorf("Expected capabilities [GET /ping], got %v", capabilities)
+	}
+}